@@ -17,26 +17,86 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-type DCOSVariant struct {
-	Name string
+// Duration is a time.Duration that is read from and written to JSON as a
+// string such as "500ms" or "1m".
+type Duration struct {
+	time.Duration
 }
 
-func (v DCOSVariant) MarshalJSON() ([]byte, error) {
-	return []byte(fmt.Sprintf("\"%s\"", v.Name)), nil
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
 }
 
-func (v DCOSVariant) String() string {
-	return v.Name
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"30s\": %s", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// SinkConfig defines a destination that tracks are delivered to. Type is one
+// of "segment", "stdout", "file" or "webhook".
+type SinkConfig struct {
+	Type    string            `json:"type"`
+	Path    string            `json:"path"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+}
+
+// GenericReporterConfig defines a reporter entirely in the signal config file.
+// The JSON responses of all endpoints are merged into one document, and every
+// extraction turns a path into that document into a track property.
+type GenericReporterConfig struct {
+	Name      string            `json:"name"`
+	Event     string            `json:"event"`
+	Endpoints []string          `json:"endpoints"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	Body      string            `json:"body"`
+	Extract   []Extraction      `json:"extract"`
 }
 
-func (v *DCOSVariant) Set(variant string) error {
-	if variant == "enterprise" {
-		initEnterprise()
-	} else if variant != "open" {
-		return fmt.Errorf("unknown variant '%s'. Only 'open' or 'enterprise' are allowed", variant)
+// Extraction maps a JSONPath-style path such as "$.apps[*].instances" to a
+// track property. Op is "value" (the default), "count" or "sum".
+type Extraction struct {
+	Property string `json:"property"`
+	Path     string `json:"path"`
+	Op       string `json:"op"`
+}
+
+// HealthDetailConfig turns on sending which nodes a unit is unhealthy on,
+// with an excerpt of its journal output. NodeIPs is "hash" (the default),
+// "plain" or "none". No excerpt is sent if ExcerptBytes is not positive.
+type HealthDetailConfig struct {
+	Enabled      bool         `json:"enabled"`
+	NodeIPs      string       `json:"node_ips"`
+	ExcerptBytes int          `json:"excerpt_bytes"`
+	Redact       RedactConfig `json:"redact"`
+}
+
+// validate returns an error if NodeIPs is not one of the known values, rather
+// than silently sending hashes when something else was asked for.
+func (h HealthDetailConfig) validate() error {
+	switch h.NodeIPs {
+	case "hash", "plain", "none":
+		return nil
 	}
-	v.Name = variant
-	return nil
+	return fmt.Errorf("health_detail.node_ips must be \"hash\", \"plain\" or \"none\", got %q", h.NodeIPs)
+}
+
+// RedactConfig defines what is scrubbed from journal excerpts. Patterns are
+// additional regular expressions whose matches are scrubbed.
+type RedactConfig struct {
+	IPs       bool     `json:"ips"`
+	Hostnames bool     `json:"hostnames"`
+	Secrets   bool     `json:"secrets"`
+	Patterns  []string `json:"patterns"`
 }
 
 // Config defines dcos-signal configuration
@@ -45,10 +105,12 @@ type Config struct {
 	DiagnosticsURLs []string `json:"diagnostics_urls"`
 	CosmosURLs      []string `json:"cosmos_urls"`
 	MesosURLs       []string `json:"mesos_urls"`
+	MarathonURLs    []string `json:"marathon_urls"`
+	MetronomeURLs   []string `json:"metronome_urls"`
 
 	// CA Configuration for TLS requests
-	CACertPath string `json:"ca_cert_path"`
-	CAPool     *x509.CertPool
+	CACertPath string         `json:"ca_cert_path"`
+	CAPool     *x509.CertPool `json:"-"`
 
 	// Segment IO Settings
 	SegmentKey   string
@@ -73,10 +135,61 @@ type Config struct {
 	FlagVersion bool
 	FlagVerbose bool
 	FlagTest    bool
+	FlagDaemon  bool
+	LeaderOnly  bool
 	Enabled     string `json:"enabled"`
 
+	// Daemon mode scheduling
+	Interval          time.Duration
+	IntervalJitter    time.Duration
+	ReporterIntervals map[string]Duration `json:"reporter_intervals"`
+
+	// Deadlines for a single run and for each reporter within it
+	RunTimeout      time.Duration
+	ReporterTimeout time.Duration
+
+	// Reporters turned on or off by name, all registered reporters run if unset
+	Reporters map[string]bool `json:"reporters"`
+
+	// Send the list of every installed package along with the package counts
+	SendPackageList bool `json:"send_package_list"`
+
+	// Read gone agents and how long agents have been unreachable from the
+	// Mesos registry, which can be large, for the maintenance reporter
+	MaintenanceRegistry bool `json:"maintenance_registry"`
+
+	// Opt-in detail on the nodes units are unhealthy on
+	HealthDetail HealthDetailConfig `json:"health_detail"`
+
+	// Reporters defined in the config file rather than in code
+	GenericReporters []GenericReporterConfig `json:"generic_reporters"`
+
+	// Destinations for tracks, segment only if empty
+	Sinks []SinkConfig `json:"sinks"`
+
+	// Spool for tracks that could not be delivered, disabled if OutboxDir is empty
+	OutboxDir      string   `json:"outbox_dir"`
+	OutboxMaxBytes int64    `json:"outbox_max_bytes"`
+	OutboxMaxAge   Duration `json:"outbox_max_age"`
+
+	// State kept between runs, e.g. for deltas, disabled if StateDir is empty
+	StateDir string `json:"state_dir"`
+
 	// Extra headers for all reporter{}'s
 	ExtraHeaders map[string]string
+
+	// Credentials for enterprise clusters. A token file is used if set,
+	// otherwise the service account, whose private key can be kept separately.
+	ServiceAccountPath string `json:"service_account_path"`
+	PrivateKeyPath     string `json:"private_key_path"`
+	TokenPath          string `json:"token_path"`
+
+	// Authentication for requests to DC/OS, nil if signal has no credentials
+	Auth *TokenProvider `json:"-"`
+
+	// Retry policy for all reporters, and per reporter name overrides
+	Retry         RetryPolicy            `json:"retry"`
+	ReporterRetry map[string]RetryPolicy `json:"reporter_retry"`
 }
 
 var (
@@ -89,12 +202,43 @@ var (
 		SignalServiceConfigPath: "/opt/mesosphere/etc/dcos-signal-config.json",
 		ExtraJSONConfigPath:     "/opt/mesosphere/etc/dcos-signal-extra.json",
 		ExtraHeaders:            make(map[string]string),
+		LeaderOnly:              true,
+		SendPackageList:         true,
+		Interval:                time.Hour,
+		IntervalJitter:          5 * time.Minute,
+		RunTimeout:              60 * time.Second,
+		ReporterTimeout:         15 * time.Second,
+		Retry:                   defaultRetryPolicy,
+		OutboxMaxBytes:          10 << 20,
+		OutboxMaxAge:            Duration{7 * 24 * time.Hour},
+		HealthDetail: HealthDetailConfig{
+			NodeIPs:      "hash",
+			ExcerptBytes: 512,
+			Redact: RedactConfig{
+				IPs:       true,
+				Hostnames: true,
+				Secrets:   true,
+			},
+		},
+		ServiceAccountPath: "/run/dcos/etc/signal-service/service_account.json",
 	}
 )
 
 // DefaultConfig returns default Config{}
 func DefaultConfig() Config {
-	return defaultConfig
+	c := defaultConfig
+	c.Retry = defaultConfig.Retry.clone()
+	c.ExtraHeaders = make(map[string]string, len(defaultConfig.ExtraHeaders))
+	for k, v := range defaultConfig.ExtraHeaders {
+		c.ExtraHeaders[k] = v
+	}
+	return c
+}
+
+// ReporterEnabled reports whether the reporter with the given name should run.
+func (c Config) ReporterEnabled(name string) bool {
+	enabled, ok := c.Reporters[name]
+	return !ok || enabled
 }
 
 func (c *Config) setFlags(fs *flag.FlagSet) {
@@ -104,8 +248,19 @@ func (c *Config) setFlags(fs *flag.FlagSet) {
 	fs.StringVar(&c.LicensingSocket, "licensing-socket", c.LicensingSocket, "Path to licensing socket.")
 	fs.StringVar(&c.SignalServiceConfigPath, "c", c.SignalServiceConfigPath, "Path to dcos-signal-service.conf.")
 	fs.StringVar(&c.SegmentKey, "segment-key", c.SegmentKey, "Key for segmentIO.")
-	fs.BoolVar(&c.FlagTest, "test", c.FlagTest, "Dump the data sent to segment to stdout.")
+	fs.BoolVar(&c.FlagTest, "test", c.FlagTest, "Dump the data to stdout instead of sending it to the configured sinks.")
 	fs.Var(&c.DCOSVariant, "dcos-variant", "Variant of DC/OS ('open' or 'enterprise')")
+	fs.StringVar(&c.ServiceAccountPath, "service-account-path", c.ServiceAccountPath, "Path to the service account JSON used to log in on enterprise clusters.")
+	fs.StringVar(&c.PrivateKeyPath, "private-key-path", c.PrivateKeyPath, "Path to the service account's private key, if not in the service account JSON.")
+	fs.StringVar(&c.TokenPath, "token-path", c.TokenPath, "Path to a pre-issued authentication token, used instead of the service account.")
+	fs.BoolVar(&c.LeaderOnly, "leader-only", c.LeaderOnly, "Only send reports from the leading Mesos master. Use -leader-only=false to report from every master.")
+	fs.StringVar(&c.OutboxDir, "outbox-dir", c.OutboxDir, "Directory to spool undelivered tracks in for the next run.")
+	fs.StringVar(&c.StateDir, "state-dir", c.StateDir, "Directory to keep state between runs in, e.g. for task deltas.")
+	fs.BoolVar(&c.FlagDaemon, "daemon", c.FlagDaemon, "Keep running and gather reports on a schedule.")
+	fs.DurationVar(&c.Interval, "interval", c.Interval, "Time between runs in daemon mode.")
+	fs.DurationVar(&c.IntervalJitter, "interval-jitter", c.IntervalJitter, "Random delay of up to this long added to each interval in daemon mode.")
+	fs.DurationVar(&c.RunTimeout, "run-timeout", c.RunTimeout, "Deadline for gathering all reports in a run.")
+	fs.DurationVar(&c.ReporterTimeout, "reporter-timeout", c.ReporterTimeout, "Deadline for gathering a single reporter's endpoints.")
 }
 
 func (c *Config) getLicenseID() error {
@@ -165,9 +320,12 @@ func (c *Config) getLicenseID() error {
 func (c *Config) getClusterID() error {
 	fileByte, err := ioutil.ReadFile(c.DCOSClusterIDPath)
 	if err != nil {
-		return err
+		return fmt.Errorf("unable to read cluster ID: %s", err)
 	}
 	c.ClusterID = strings.TrimSpace(string(fileByte))
+	if c.ClusterID == "" {
+		return fmt.Errorf("cluster ID file %s is empty", c.DCOSClusterIDPath)
+	}
 	log.Debugf("Detected Cluster ID: %s", c.ClusterID)
 	return nil
 }
@@ -221,9 +379,13 @@ func ParseArgsReturnConfig(args []string) (Config, []error) {
 	signalFlag := flag.NewFlagSet("", flag.ContinueOnError)
 	c.setFlags(signalFlag)
 
-	// Parse CLI flags to override default config
-	if err := signalFlag.Parse(args); err != nil {
-		errAry = append(errAry, err)
+	// Environment variables and CLI flags can both say where the rest of the
+	// config is, so read them before anything else.
+	envErrs := c.loadEnv(signalFlag, os.LookupEnv)
+	errAry = append(errAry, envErrs...)
+	flagErr := signalFlag.Parse(args)
+	if flagErr != nil {
+		errAry = append(errAry, flagErr)
 	}
 
 	// Not all clusters will have a license, including open source clusters.
@@ -231,24 +393,38 @@ func ParseArgsReturnConfig(args []string) (Config, []error) {
 		log.Errorf("error getting LicenseID. Got error: %v", err)
 	}
 
-	// Get the cluster-id generate by ZK consensus
+	// Get the cluster-id generate by ZK consensus. If it is not found signal
+	// service should fail, but the rest of the config is still loaded so that
+	// all problems are reported at once.
 	if err := c.getClusterID(); err != nil {
-		// If cluster-id is not found signal service should fail.
 		errAry = append(errAry, err)
-		return c, errAry
 	}
 
 	// Get standard and extra JSON config off disk
 	if err := c.getExternalConfig(); err != nil {
-		c.GenPlatform = err.Error()
-		c.GenProvider = err.Error()
-		c.CustomerKey = err.Error()
+		errAry = append(errAry, err)
+	}
+
+	// Environment variables override the JSON config, and CLI flags override
+	// both. Errors were already reported the first time around.
+	c.loadEnv(signalFlag, os.LookupEnv)
+	if flagErr == nil {
+		signalFlag.Parse(args)
+	}
+
+	if err := c.HealthDetail.validate(); err != nil {
 		errAry = append(errAry, err)
 	}
 
 	// Once all the config has been loaded, we can attempted to make a CAPool from the
 	// path passed in config
 	if err := c.tryLoadingCert(); err != nil {
+		errAry = append(errAry, fmt.Errorf("unable to load CA certificate %s: %s", c.CACertPath, err))
+	}
+
+	// Variant specific setup, like enterprise auth, can depend on any of the
+	// config sources, so it runs after all of them are loaded.
+	if err := c.InitVariant(); err != nil {
 		errAry = append(errAry, err)
 	}
 
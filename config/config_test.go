@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -174,3 +175,77 @@ NfOBa2wja44Izn/W58mcHkIvK61BCWGKTg==
 		t.Error("Expected CAPool, got", mockC.CAPool)
 	}
 }
+
+func TestRetryPolicyFor(t *testing.T) {
+	config := []byte(`
+{
+"retry": {"base_backoff": "1s"},
+"reporter_retry": {"cosmos": {"max_attempts": 5, "retry_status_codes": [500]}}
+}`)
+	configFile, _ := ioutil.TempFile(os.TempDir(), "")
+	defer os.Remove(configFile.Name())
+	configFile.Write(config)
+
+	c := DefaultConfig()
+	c.SignalServiceConfigPath = configFile.Name()
+	if err := c.getExternalConfig(); err != nil {
+		t.Fatal("Expected no errors loading config, got", err)
+	}
+
+	mesos := c.RetryPolicyFor("mesos")
+	if mesos.MaxAttempts != 3 {
+		t.Error("Expected default of 3 attempts for mesos, got", mesos.MaxAttempts)
+	}
+	if mesos.BaseBackoff.Duration != time.Second {
+		t.Error("Expected base backoff of 1s, got", mesos.BaseBackoff)
+	}
+
+	cosmos := c.RetryPolicyFor("cosmos")
+	if cosmos.MaxAttempts != 5 {
+		t.Error("Expected 5 attempts for cosmos, got", cosmos.MaxAttempts)
+	}
+	if cosmos.BaseBackoff.Duration != time.Second {
+		t.Error("Expected cosmos to inherit base backoff of 1s, got", cosmos.BaseBackoff)
+	}
+	if !cosmos.ShouldRetryStatus(500) || cosmos.ShouldRetryStatus(503) {
+		t.Error("Expected cosmos to only retry 500, got", cosmos.RetryStatusCodes)
+	}
+	if !cosmos.ShouldRetryNetworkErrors() {
+		t.Error("Expected cosmos to inherit retrying network errors")
+	}
+
+	if d := DefaultConfig(); d.Retry.BaseBackoff.Duration == time.Second {
+		t.Error("Expected loading config to leave defaults untouched")
+	}
+}
+
+func TestHealthDetailValidate(t *testing.T) {
+	hd := DefaultConfig().HealthDetail
+	for _, nodeIPs := range []string{"hash", "plain", "none"} {
+		hd.NodeIPs = nodeIPs
+		if err := hd.validate(); err != nil {
+			t.Errorf("Expected nil error for node_ips %s, got %s", nodeIPs, err)
+		}
+	}
+	for _, nodeIPs := range []string{"", "ip", "Hash"} {
+		hd.NodeIPs = nodeIPs
+		if err := hd.validate(); err == nil {
+			t.Errorf("Expected error for node_ips %q, got nil", nodeIPs)
+		}
+	}
+}
+
+func TestReporterEnabled(t *testing.T) {
+	c := DefaultConfig()
+	c.Reporters = map[string]bool{"cosmos": false, "mesos": true}
+
+	if c.ReporterEnabled("cosmos") {
+		t.Error("Expected cosmos to be disabled")
+	}
+	if !c.ReporterEnabled("mesos") {
+		t.Error("Expected mesos to be enabled")
+	}
+	if !c.ReporterEnabled("diagnostics") {
+		t.Error("Expected unlisted reporters to be enabled")
+	}
+}
@@ -8,55 +8,128 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	log "github.com/sirupsen/logrus"
 )
 
-func initEnterprise() {
-	token, err := generateJWTToken()
-	if err != nil {
-		log.Fatalf("Unable to generate JWT token: %s", err)
-		os.Exit(1)
+// privateKeyEnv is the environment variable the service account's private key
+// can be passed in, instead of in the service account file.
+const privateKeyEnv = "DCOS_SIGNAL_PRIVATE_KEY"
+
+// serviceAccount defines the JSON of the service account signal logs in to
+// Bouncer with.
+type serviceAccount struct {
+	UID           string `json:"uid"`
+	PrivateKey    string `json:"private_key"`
+	LoginEndpoint string `json:"login_endpoint"`
+}
+
+func init() {
+	RegisterVariant("enterprise", (*Config).initAuth)
+}
+
+// initAuth sets up authentication for enterprise clusters from the first
+// credential source that works: a pre-issued token file if one is configured,
+// else the service account. It logs in right away, so broken credentials fail
+// at startup rather than in every reporter.
+func (c *Config) initAuth() error {
+	var (
+		auth   *TokenProvider
+		errMsg []string
+	)
+	if c.TokenPath != "" {
+		auth = NewTokenProvider(tokenFileLogin(c.TokenPath))
+		if _, err := auth.Token(); err != nil {
+			errMsg = append(errMsg, fmt.Sprintf("token file: %s", err))
+			auth = nil
+		}
+	}
+
+	if auth == nil {
+		sa, err := c.loadServiceAccount()
+		if err == nil {
+			auth = NewTokenProvider(sa.login)
+			_, err = auth.Token()
+		}
+		if err != nil {
+			errMsg = append(errMsg, fmt.Sprintf("service account: %s", err))
+			auth = nil
+		}
 	}
 
-	defaultConfig.DCOSVariant = DCOSVariant{"enterprise"}
-	defaultConfig.ExtraHeaders["Authorization"] = fmt.Sprintf("token=%s", token)
+	if auth == nil {
+		return fmt.Errorf("enterprise variant needs credentials, but none work: %s", strings.Join(errMsg, "; "))
+	}
+	c.Auth = auth
+	return nil
 }
 
-func generateJWTToken() (string, error) {
-	securityConfig := struct {
-		UID            string `json:"uid"`
-		PrivateKey     string `json:"private_key"`
-		LoginEndpoint  string `json:"login_endpoint"`
-		JWTToken       string
-		SecretJSONPath string
-	}{
-		SecretJSONPath: "/run/dcos/etc/signal-service/service_account.json",
+// loadServiceAccount reads the service account from c.ServiceAccountPath. The
+// private key is read from c.PrivateKeyPath or the DCOS_SIGNAL_PRIVATE_KEY
+// environment variable instead, if either is set.
+func (c *Config) loadServiceAccount() (serviceAccount, error) {
+	var sa serviceAccount
+	secretJSON, err := ioutil.ReadFile(c.ServiceAccountPath)
+	if err != nil {
+		return sa, err
 	}
-	// Load the secret file if it exists
-	secretJSON, loadErr := ioutil.ReadFile(securityConfig.SecretJSONPath)
-	if loadErr != nil {
-		log.Warn("Service account not detected, continuing with out secure requests.")
-		return "", nil
+	if err := json.Unmarshal(secretJSON, &sa); err != nil {
+		return sa, fmt.Errorf("%s: %s", c.ServiceAccountPath, err)
 	}
 
-	if jsonErr := json.Unmarshal(secretJSON, &securityConfig); jsonErr != nil {
-		return "", jsonErr
+	switch {
+	case c.PrivateKeyPath != "":
+		key, err := ioutil.ReadFile(c.PrivateKeyPath)
+		if err != nil {
+			return sa, err
+		}
+		sa.PrivateKey = string(key)
+	case os.Getenv(privateKeyEnv) != "":
+		sa.PrivateKey = os.Getenv(privateKeyEnv)
 	}
+	return sa, nil
+}
+
+// tokenFileLogin returns a LoginFunc that reads a pre-issued token from path.
+// The file is read again whenever a new token is needed, so whatever issues
+// the token can replace it.
+func tokenFileLogin(path string) LoginFunc {
+	return func() (string, time.Time, error) {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		token := strings.TrimSpace(string(b))
+		if token == "" {
+			return "", time.Time{}, fmt.Errorf("%s is empty", path)
+		}
+		return token, tokenExpiry(token, time.Now()), nil
+	}
+}
 
-	if securityConfig.UID == "" || securityConfig.PrivateKey == "" || securityConfig.LoginEndpoint == "" {
-		return "", errors.New("UID, private key or login endpoint can not be empty.")
+// login logs in to Bouncer with a login token signed by the service account's
+// private key, and returns the authentication token Bouncer responds with.
+func (sa serviceAccount) login() (string, time.Time, error) {
+	if sa.UID == "" || sa.PrivateKey == "" || sa.LoginEndpoint == "" {
+		return "", time.Time{}, errors.New("UID, private key or login endpoint can not be empty.")
 	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(sa.PrivateKey))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
 	log.Debug("Generating JWT token...")
 	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
-		"uid": securityConfig.UID,
+		"uid": sa.UID,
 		"exp": time.Now().Add(time.Hour).Unix(),
 	})
-	tokenStr, err := token.SignedString([]byte(securityConfig.PrivateKey))
+	tokenStr, err := token.SignedString(key)
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 
 	client := http.Client{
@@ -67,29 +140,30 @@ func generateJWTToken() (string, error) {
 		UID   string `json:"uid"`
 		Token string `json:"token,omitempty"`
 	}{
-		UID:   securityConfig.UID,
+		UID:   sa.UID,
 		Token: tokenStr,
 	}
 
 	b, err := json.Marshal(authReq)
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 
+	issued := time.Now()
 	authBody := bytes.NewBuffer(b)
-	req, err := http.NewRequest("POST", securityConfig.LoginEndpoint, authBody)
+	req, err := http.NewRequest("POST", sa.LoginEndpoint, authBody)
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 	req.Header.Add("Content-type", "application/json")
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to auth with Bouncer,  status code: %d", resp.StatusCode)
+		return "", time.Time{}, fmt.Errorf("failed to auth with Bouncer,  status code: %d", resp.StatusCode)
 	}
 
 	var authResp struct {
@@ -97,9 +171,10 @@ func generateJWTToken() (string, error) {
 	}
 
 	if err = json.NewDecoder(resp.Body).Decode(&authResp); err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 
-	log.Debugf("Successfully retrieved JWT token: %s", authResp.Token)
-	return authResp.Token, nil
+	expires := tokenExpiry(authResp.Token, issued)
+	log.Debugf("Successfully retrieved JWT token, expires %s", expires.Format(time.RFC3339))
+	return authResp.Token, expires, nil
 }
@@ -0,0 +1,79 @@
+// +build unit
+
+package config
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInitAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signal-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	bouncer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"token": "from-bouncer"})
+	}))
+	defer bouncer.Close()
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	saNoKey := write("sa-no-key.json", `{"uid": "signal", "login_endpoint": "`+bouncer.URL+`"}`)
+	keyPath := write("key.pem", string(keyPEM))
+	tokenPath := write("token", "pre-issued\n")
+
+	for _, tc := range []struct {
+		name     string
+		c        Config
+		env      string
+		expected string
+	}{
+		{"token file", Config{TokenPath: tokenPath, ServiceAccountPath: saNoKey}, "", "pre-issued"},
+		{"private key file", Config{ServiceAccountPath: saNoKey, PrivateKeyPath: keyPath}, "", "from-bouncer"},
+		{"private key env", Config{ServiceAccountPath: saNoKey}, string(keyPEM), "from-bouncer"},
+		{"token file falls back", Config{TokenPath: filepath.Join(dir, "missing"), ServiceAccountPath: saNoKey, PrivateKeyPath: keyPath}, "", "from-bouncer"},
+	} {
+		os.Setenv(privateKeyEnv, tc.env)
+		if err := tc.c.initAuth(); err != nil {
+			t.Errorf("%s: expected nil error, got %s", tc.name, err)
+			continue
+		}
+		if token, _ := tc.c.Auth.Token(); token != tc.expected {
+			t.Errorf("%s: expected token %s, got %s", tc.name, tc.expected, token)
+		}
+	}
+	os.Unsetenv(privateKeyEnv)
+
+	c := Config{TokenPath: write("empty-token", ""), ServiceAccountPath: saNoKey}
+	err = c.initAuth()
+	if err == nil || c.Auth != nil {
+		t.Fatal("Expected error without working credentials, got", err)
+	}
+	if !strings.Contains(err.Error(), "token file") || !strings.Contains(err.Error(), "service account") {
+		t.Error("Expected error to name every credential source tried, got", err)
+	}
+}
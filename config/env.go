@@ -0,0 +1,116 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// envPrefix starts the names of all environment variables signal reads its
+// config from.
+const envPrefix = "DCOS_SIGNAL_"
+
+// envFlagNames names the environment variables for flags whose names are
+// too short to make sense on their own.
+var envFlagNames = map[string]string{
+	"c": "CONFIG",
+	"v": "VERBOSE",
+}
+
+// envVar is a config setting that can be set with an environment variable.
+type envVar struct {
+	name string
+	set  func(string) error
+}
+
+// envVars returns the environment variables every config setting can be set
+// with: DCOS_SIGNAL_ followed by the setting's JSON key or flag name in upper
+// case, e.g. DCOS_SIGNAL_MESOS_URLS or DCOS_SIGNAL_SEGMENT_KEY. Settings that
+// have neither get a name of their own.
+func (c *Config) envVars(fs *flag.FlagSet) []envVar {
+	var (
+		vars []envVar
+		seen = make(map[string]bool)
+		add  = func(name string, set func(string) error) {
+			name = envPrefix + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+			if !seen[name] {
+				seen[name] = true
+				vars = append(vars, envVar{name, set})
+			}
+		}
+	)
+
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		key := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+		field := v.Field(i).Addr().Interface()
+		add(key, func(s string) error { return setFromEnv(field, s) })
+	}
+
+	fs.VisitAll(func(f *flag.Flag) {
+		name := f.Name
+		if n, ok := envFlagNames[name]; ok {
+			name = n
+		}
+		add(name, f.Value.Set)
+	})
+
+	add("segment_event", func(s string) error { return setFromEnv(&c.SegmentEvent, s) })
+	add("dcos_version", func(s string) error { return setFromEnv(&c.DCOSVersion, s) })
+	add("extra_config", func(s string) error { return setFromEnv(&c.ExtraJSONConfigPath, s) })
+	add("extra_headers", func(s string) error { return setFromEnv(&c.ExtraHeaders, s) })
+
+	sort.Slice(vars, func(i, j int) bool { return vars[i].name < vars[j].name })
+	return vars
+}
+
+// setFromEnv sets the value field points to from an environment variable.
+// Strings are taken as they are, lists of strings can be given as a comma
+// separated list, and everything else is read as JSON, like in the config file.
+// Values that are strings in JSON, like durations, can be given without quotes.
+func setFromEnv(field interface{}, s string) error {
+	switch f := field.(type) {
+	case *string:
+		*f = s
+		return nil
+	case *[]string:
+		if !strings.HasPrefix(strings.TrimSpace(s), "[") {
+			*f = nil
+			for _, item := range strings.Split(s, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					*f = append(*f, item)
+				}
+			}
+			return nil
+		}
+	}
+
+	err := json.Unmarshal([]byte(s), field)
+	if err != nil && json.Unmarshal([]byte(strconv.Quote(s)), field) == nil {
+		return nil
+	}
+	return err
+}
+
+// loadEnv sets everything there is an environment variable for, as returned
+// by lookup.
+func (c *Config) loadEnv(fs *flag.FlagSet, lookup func(string) (string, bool)) []error {
+	var errs []error
+	for _, ev := range c.envVars(fs) {
+		s, ok := lookup(ev.name)
+		if !ok {
+			continue
+		}
+		if err := ev.set(s); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s: %s", ev.name, err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,125 @@
+// +build unit
+
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadEnv(t *testing.T) {
+	env := map[string]string{
+		"DCOS_SIGNAL_MESOS_URLS":        "http://a:5050, http://b:5050",
+		"DCOS_SIGNAL_COSMOS_URLS":       `["http://cosmos"]`,
+		"DCOS_SIGNAL_CA_CERT_PATH":      "/ca.crt",
+		"DCOS_SIGNAL_CUSTOMER_KEY":      "ck",
+		"DCOS_SIGNAL_SEGMENT_KEY":       "sk",
+		"DCOS_SIGNAL_ENABLED":           "false",
+		"DCOS_SIGNAL_EXTRA_HEADERS":     `{"X-Foo": "bar"}`,
+		"DCOS_SIGNAL_LEADER_ONLY":       "false",
+		"DCOS_SIGNAL_INTERVAL":          "10m",
+		"DCOS_SIGNAL_OUTBOX_MAX_AGE":    "1h",
+		"DCOS_SIGNAL_SEND_PACKAGE_LIST": "false",
+		"DCOS_SIGNAL_CONFIG":            "/signal.json",
+		"DCOS_SIGNAL_RETRY":             `{"max_attempts": 5}`,
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	c := DefaultConfig()
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	c.setFlags(fs)
+	if errs := c.loadEnv(fs, lookup); len(errs) != 0 {
+		t.Fatal("Expected no errors, got", errs)
+	}
+
+	if !reflect.DeepEqual(c.MesosURLs, []string{"http://a:5050", "http://b:5050"}) {
+		t.Error("Expected comma separated mesos URLs, got", c.MesosURLs)
+	}
+	if !reflect.DeepEqual(c.CosmosURLs, []string{"http://cosmos"}) {
+		t.Error("Expected JSON list of cosmos URLs, got", c.CosmosURLs)
+	}
+	if c.CACertPath != "/ca.crt" || c.CustomerKey != "ck" || c.SegmentKey != "sk" || c.Enabled != "false" {
+		t.Error("Expected string settings from env, got", c.CACertPath, c.CustomerKey, c.SegmentKey, c.Enabled)
+	}
+	if c.ExtraHeaders["X-Foo"] != "bar" {
+		t.Error("Expected extra header from env, got", c.ExtraHeaders)
+	}
+	if len(DefaultConfig().ExtraHeaders) != 0 {
+		t.Error("Expected default headers to be left alone, got", DefaultConfig().ExtraHeaders)
+	}
+	if c.LeaderOnly || c.SendPackageList {
+		t.Error("Expected bools from env to be false, got", c.LeaderOnly, c.SendPackageList)
+	}
+	if c.Interval != 10*time.Minute || c.OutboxMaxAge.Duration != time.Hour {
+		t.Error("Expected durations from env, got", c.Interval, c.OutboxMaxAge)
+	}
+	if c.SignalServiceConfigPath != "/signal.json" {
+		t.Error("Expected config path from env, got", c.SignalServiceConfigPath)
+	}
+	if c.Retry.MaxAttempts != 5 || c.Retry.BaseBackoff.Duration != 500*time.Millisecond {
+		t.Error("Expected max attempts from env over the default policy, got", c.Retry)
+	}
+
+	env = map[string]string{"DCOS_SIGNAL_LEADER_ONLY": "maybe", "DCOS_SIGNAL_OUTBOX_MAX_BYTES": "lots"}
+	if errs := c.loadEnv(fs, lookup); len(errs) != 2 {
+		t.Error("Expected an error for every invalid value, got", errs)
+	}
+}
+
+func TestParseArgsPrecedence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signal-env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	clusterID := write("cluster-id", "12345")
+	configPath := write("config.json", `{"customer_key": "json", "gen_platform": "json", "gen_provider": "json", "license_id": "json", "outbox_dir": "json"}`)
+	extraPath := write("extra.json", `{"gen_provider": "extra", "license_id": "extra", "outbox_dir": "extra"}`)
+
+	for k, v := range map[string]string{
+		"DCOS_SIGNAL_EXTRA_CONFIG":    extraPath,
+		"DCOS_SIGNAL_CLUSTER_ID_PATH": clusterID,
+		"DCOS_SIGNAL_LICENSE_ID":      "env",
+		"DCOS_SIGNAL_OUTBOX_DIR":      "env",
+	} {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	c, errs := ParseArgsReturnConfig([]string{"-c", configPath, "-outbox-dir", "flag"})
+	if errs != nil {
+		t.Fatal("Expected no errors, got", errs)
+	}
+
+	for _, tc := range []struct {
+		setting, actual, expected string
+	}{
+		{"segment event", c.SegmentEvent, "health"},
+		{"customer key", c.CustomerKey, "json"},
+		{"platform", c.GenPlatform, "json"},
+		{"provider", c.GenProvider, "extra"},
+		{"license ID", c.LicenseID, "env"},
+		{"outbox dir", c.OutboxDir, "flag"},
+		{"cluster ID", c.ClusterID, "12345"},
+	} {
+		if tc.actual != tc.expected {
+			t.Errorf("Expected %s from %s, got %s", tc.setting, tc.expected, tc.actual)
+		}
+	}
+}
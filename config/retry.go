@@ -0,0 +1,100 @@
+package config
+
+import (
+	"net/http"
+	"time"
+)
+
+// RetryPolicy defines how a reporter retries a failed request to one of its
+// endpoints.
+type RetryPolicy struct {
+	// Total number of attempts, including the first one
+	MaxAttempts int `json:"max_attempts"`
+	// Backoff before the second attempt, doubled for every attempt after that
+	BaseBackoff Duration `json:"base_backoff"`
+	// Upper bound for the backoff between two attempts
+	MaxBackoff Duration `json:"max_backoff"`
+	// Fraction (0-1) of each backoff that is randomized
+	Jitter float64 `json:"jitter"`
+	// Response status codes that are worth another attempt
+	RetryStatusCodes []int `json:"retry_status_codes"`
+	// Whether connection errors and timeouts are worth another attempt
+	RetryNetworkErrors *bool `json:"retry_network_errors"`
+}
+
+var (
+	retryNetworkErrors = true
+
+	defaultRetryPolicy = RetryPolicy{
+		MaxAttempts: 3,
+		BaseBackoff: Duration{500 * time.Millisecond},
+		MaxBackoff:  Duration{5 * time.Second},
+		Jitter:      0.2,
+		RetryStatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		RetryNetworkErrors: &retryNetworkErrors,
+	}
+)
+
+// clone returns a copy of p that shares no memory with it, so decoding JSON into
+// the copy leaves p untouched.
+func (p RetryPolicy) clone() RetryPolicy {
+	p.RetryStatusCodes = append([]int(nil), p.RetryStatusCodes...)
+	if p.RetryNetworkErrors != nil {
+		retry := *p.RetryNetworkErrors
+		p.RetryNetworkErrors = &retry
+	}
+	return p
+}
+
+// RetryPolicyFor returns the retry policy for the reporter with the given name.
+// Fields that are not set in the reporter's entry of ReporterRetry are taken
+// from Retry.
+func (c Config) RetryPolicyFor(name string) RetryPolicy {
+	policy := c.Retry
+	override, ok := c.ReporterRetry[name]
+	if !ok {
+		return policy
+	}
+
+	if override.MaxAttempts != 0 {
+		policy.MaxAttempts = override.MaxAttempts
+	}
+	if override.BaseBackoff.Duration != 0 {
+		policy.BaseBackoff = override.BaseBackoff
+	}
+	if override.MaxBackoff.Duration != 0 {
+		policy.MaxBackoff = override.MaxBackoff
+	}
+	if override.Jitter != 0 {
+		policy.Jitter = override.Jitter
+	}
+	if override.RetryStatusCodes != nil {
+		policy.RetryStatusCodes = override.RetryStatusCodes
+	}
+	if override.RetryNetworkErrors != nil {
+		policy.RetryNetworkErrors = override.RetryNetworkErrors
+	}
+	return policy
+}
+
+// ShouldRetryStatus reports whether a response with the given status code is
+// worth another attempt.
+func (p RetryPolicy) ShouldRetryStatus(code int) bool {
+	for _, c := range p.RetryStatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// ShouldRetryNetworkErrors reports whether connection errors and timeouts are
+// worth another attempt.
+func (p RetryPolicy) ShouldRetryNetworkErrors() bool {
+	return p.RetryNetworkErrors != nil && *p.RetryNetworkErrors
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Log in again this long before the current token expires, so requests
+	// in flight don't race its expiry.
+	tokenRefreshBefore = 5 * time.Minute
+
+	// Assumed lifetime of tokens that don't say when they expire
+	defaultTokenLifetime = time.Hour
+)
+
+// LoginFunc logs in to DC/OS and returns an authentication token and when it
+// expires.
+type LoginFunc func() (token string, expires time.Time, err error)
+
+// TokenProvider hands out the token signal authenticates to DC/OS with. It
+// keeps the token until shortly before it expires or until a request is
+// rejected with it, then logs in again. It is safe for concurrent use, and
+// concurrent callers share a single login.
+type TokenProvider struct {
+	login LoginFunc
+	now   func() time.Time
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewTokenProvider returns a TokenProvider that logs in with login.
+func NewTokenProvider(login LoginFunc) *TokenProvider {
+	return &TokenProvider{
+		login: login,
+		now:   time.Now,
+	}
+}
+
+// Token returns a token that is valid for at least a few more minutes,
+// logging in if there is none.
+func (p *TokenProvider) Token() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token != "" && p.now().Add(tokenRefreshBefore).Before(p.expires) {
+		return p.token, nil
+	}
+
+	log.Debug("Logging in for a new authentication token")
+	token, expires, err := p.login()
+	if err != nil {
+		return "", err
+	}
+	p.token, p.expires = token, expires
+	return token, nil
+}
+
+// Invalidate drops token if it is the current one, so the next call to Token
+// logs in again. Tokens that have already been replaced are ignored, so that
+// several requests rejected with the same token cause only one login.
+func (p *TokenProvider) Invalidate(token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token == token {
+		p.token = ""
+	}
+}
+
+// tokenExpiry returns when token expires according to its exp claim, or
+// defaultTokenLifetime after issued if it doesn't tell. The token is not
+// verified, signal only needs to know when to ask for a new one.
+func tokenExpiry(token string, issued time.Time) time.Time {
+	var claims jwt.StandardClaims
+	if _, _, err := new(jwt.Parser).ParseUnverified(token, &claims); err != nil || claims.ExpiresAt == 0 {
+		return issued.Add(defaultTokenLifetime)
+	}
+	return time.Unix(claims.ExpiresAt, 0)
+}
@@ -0,0 +1,125 @@
+// +build unit
+
+package config
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func TestTokenProvider(t *testing.T) {
+	var (
+		now    = time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+		logins int
+		fail   bool
+	)
+	p := NewTokenProvider(func() (string, time.Time, error) {
+		if fail {
+			return "", time.Time{}, errors.New("bouncer unavailable")
+		}
+		logins++
+		return string(rune('a' + logins - 1)), now.Add(time.Hour), nil
+	})
+	p.now = func() time.Time { return now }
+
+	if token, err := p.Token(); err != nil || token != "a" {
+		t.Fatal("Expected token a, got", token, err)
+	}
+	if token, _ := p.Token(); token != "a" || logins != 1 {
+		t.Error("Expected cached token a, got", token, logins)
+	}
+
+	// Shortly before it expires the token is replaced.
+	now = now.Add(time.Hour - tokenRefreshBefore)
+	if token, _ := p.Token(); token != "b" || logins != 2 {
+		t.Error("Expected new token b before expiry, got", token, logins)
+	}
+
+	// Only the current token can be invalidated.
+	p.Invalidate("a")
+	if token, _ := p.Token(); token != "b" {
+		t.Error("Expected token b after invalidating a stale token, got", token)
+	}
+	p.Invalidate("b")
+	if token, _ := p.Token(); token != "c" {
+		t.Error("Expected token c after invalidating b, got", token)
+	}
+
+	p.Invalidate("c")
+	fail = true
+	if _, err := p.Token(); err == nil {
+		t.Error("Expected error when login fails, got nil")
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	issued := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	exp := issued.Add(5 * 24 * time.Hour)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"uid": "signal",
+		"exp": exp.Unix(),
+	}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expires := tokenExpiry(token, issued); !expires.Equal(exp) {
+		t.Error("Expected expiry from exp claim", exp, "got", expires)
+	}
+	if expires := tokenExpiry("not-a-jwt", issued); !expires.Equal(issued.Add(defaultTokenLifetime)) {
+		t.Error("Expected default lifetime for opaque token, got", expires)
+	}
+}
+
+func TestServiceAccountLogin(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	exp := time.Now().Add(5 * 24 * time.Hour).Unix()
+	bouncer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			UID   string `json:"uid"`
+			Token string `json:"token"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if _, err := jwt.Parse(req.Token, func(*jwt.Token) (interface{}, error) { return &key.PublicKey, nil }); err != nil || req.UID != "signal" {
+			http.Error(w, http.StatusText(401), 401)
+			return
+		}
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"exp": exp}).SignedString([]byte("bouncer"))
+		json.NewEncoder(w).Encode(map[string]string{"token": token})
+	}))
+	defer bouncer.Close()
+
+	sa := serviceAccount{UID: "signal", PrivateKey: string(keyPEM), LoginEndpoint: bouncer.URL}
+	token, expires, err := sa.login()
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if token == "" || expires.Unix() != exp {
+		t.Error("Expected token expiring at", exp, "got", token, expires)
+	}
+
+	sa.UID = "someone-else"
+	if _, _, err := sa.login(); err == nil {
+		t.Error("Expected error for rejected login, got nil")
+	}
+
+	sa.PrivateKey = "not a key"
+	if _, _, err := sa.login(); err == nil {
+		t.Error("Expected error for invalid private key, got nil")
+	}
+}
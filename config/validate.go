@@ -0,0 +1,213 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// mask replaces secrets in the output of print-config
+const mask = "********"
+
+// ValidateArgs loads the config from every source like ParseArgsReturnConfig,
+// and checks it more strictly: the config files may only have known keys, and
+// all URLs need to be absolute http or https URLs. It returns every problem it
+// finds, not just the first.
+func ValidateArgs(args []string) (Config, []error) {
+	c, errs := ParseArgsReturnConfig(args)
+	for _, path := range []string{c.SignalServiceConfigPath, c.ExtraJSONConfigPath} {
+		errs = append(errs, unknownKeys(path)...)
+	}
+	errs = append(errs, c.validateURLs()...)
+	return c, errs
+}
+
+// unknownKeys returns an error for every key in the JSON config file at path
+// that does not match a setting. A missing extra config file is fine, and
+// other problems reading the file are already reported by getExternalConfig.
+func unknownKeys(path string) []error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var doc interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil
+	}
+
+	var keys []string
+	walkUnknownKeys(doc, reflect.TypeOf(Config{}), "", &keys)
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		errs = append(errs, fmt.Errorf("%s: unknown key %s", path, key))
+	}
+	return errs
+}
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// walkUnknownKeys adds the path of every key in doc that t has no field for to
+// keys. Keys match fields like encoding/json matches them: by JSON tag, or by
+// field name without one, ignoring case.
+func walkUnknownKeys(doc interface{}, t reflect.Type, path string, keys *[]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(unmarshalerType) {
+		return
+	}
+
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Map:
+			for k, item := range v {
+				walkUnknownKeys(item, t.Elem(), path+"."+k, keys)
+			}
+		case reflect.Struct:
+			fields := make(map[string]reflect.Type)
+			for i := 0; i < t.NumField(); i++ {
+				f := t.Field(i)
+				name := strings.Split(f.Tag.Get("json"), ",")[0]
+				if name == "-" || f.PkgPath != "" {
+					continue
+				}
+				if name == "" {
+					name = f.Name
+				}
+				fields[strings.ToLower(name)] = f.Type
+			}
+			for k, item := range v {
+				ft, ok := fields[strings.ToLower(k)]
+				if !ok {
+					*keys = append(*keys, strings.TrimPrefix(path+"."+k, "."))
+					continue
+				}
+				walkUnknownKeys(item, ft, path+"."+k, keys)
+			}
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, item := range v {
+				walkUnknownKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), keys)
+			}
+		}
+	}
+}
+
+// validateURLs returns an error for every URL in c that signal could not
+// request.
+func (c Config) validateURLs() []error {
+	var errs []error
+	check := func(setting string, urls []string) {
+		for _, u := range urls {
+			if err := checkURL(u); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s", setting, err))
+			}
+		}
+	}
+
+	check("diagnostics_urls", c.DiagnosticsURLs)
+	check("cosmos_urls", c.CosmosURLs)
+	check("mesos_urls", c.MesosURLs)
+	check("marathon_urls", c.MarathonURLs)
+	check("metronome_urls", c.MetronomeURLs)
+	for i, gc := range c.GenericReporters {
+		check(fmt.Sprintf("generic_reporters[%d].endpoints", i), gc.Endpoints)
+	}
+	return errs
+}
+
+// checkURL returns an error unless u is an absolute http or https URL.
+func checkURL(u string) error {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("'%s' is not an http or https URL", u)
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("'%s' has no host", u)
+	}
+	return nil
+}
+
+// Masked returns a copy of c that is safe to show: keys and header values are
+// replaced by a mask, and passwords and query parameter values in URLs are
+// redacted. c is left untouched.
+func (c Config) Masked() Config {
+	maskString := func(s string) string {
+		if s == "" {
+			return s
+		}
+		return mask
+	}
+	maskHeaders := func(headers map[string]string) map[string]string {
+		if headers == nil {
+			return nil
+		}
+		masked := make(map[string]string, len(headers))
+		for k, v := range headers {
+			masked[k] = maskString(v)
+		}
+		return masked
+	}
+	maskURLs := func(urls []string) []string {
+		if urls == nil {
+			return nil
+		}
+		masked := make([]string, len(urls))
+		for i, u := range urls {
+			masked[i] = maskURL(u)
+		}
+		return masked
+	}
+
+	c.SegmentKey = maskString(c.SegmentKey)
+	c.CustomerKey = maskString(c.CustomerKey)
+	c.ExtraHeaders = maskHeaders(c.ExtraHeaders)
+
+	c.DiagnosticsURLs = maskURLs(c.DiagnosticsURLs)
+	c.CosmosURLs = maskURLs(c.CosmosURLs)
+	c.MesosURLs = maskURLs(c.MesosURLs)
+	c.MarathonURLs = maskURLs(c.MarathonURLs)
+	c.MetronomeURLs = maskURLs(c.MetronomeURLs)
+
+	c.GenericReporters = append([]GenericReporterConfig(nil), c.GenericReporters...)
+	for i := range c.GenericReporters {
+		c.GenericReporters[i].Endpoints = maskURLs(c.GenericReporters[i].Endpoints)
+		c.GenericReporters[i].Headers = maskHeaders(c.GenericReporters[i].Headers)
+	}
+	c.Sinks = append([]SinkConfig(nil), c.Sinks...)
+	for i := range c.Sinks {
+		c.Sinks[i].URL = maskURL(c.Sinks[i].URL)
+		c.Sinks[i].Headers = maskHeaders(c.Sinks[i].Headers)
+	}
+	return c
+}
+
+// maskURL masks the password in u, if it has one, the way net/url does, and
+// the values of its query parameters, which can hold tokens as well.
+func maskURL(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	if parsed.RawQuery != "" {
+		query := parsed.Query()
+		for _, values := range query {
+			for i := range values {
+				values[i] = "xxxxx"
+			}
+		}
+		parsed.RawQuery = query.Encode()
+	}
+	return parsed.Redacted()
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// VariantInit sets up what is specific to a DC/OS variant, e.g. auth, once
+// all config is loaded. It can change any part of the Config.
+type VariantInit func(*Config) error
+
+var (
+	variantsMu sync.Mutex
+	variants   = map[string]VariantInit{
+		"open": nil,
+	}
+)
+
+// RegisterVariant makes a DC/OS variant selectable with -dcos-variant, with
+// init run by InitVariant when it is selected. It is meant to be called from
+// the init function of a package, and panics if the name is already taken.
+func RegisterVariant(name string, init VariantInit) {
+	variantsMu.Lock()
+	defer variantsMu.Unlock()
+
+	if _, dup := variants[name]; dup {
+		panic("config: RegisterVariant called twice for " + name)
+	}
+	variants[name] = init
+}
+
+// variantNames returns the names of all registered variants, sorted.
+func variantNames() []string {
+	variantsMu.Lock()
+	defer variantsMu.Unlock()
+
+	names := make([]string, 0, len(variants))
+	for name := range variants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type DCOSVariant struct {
+	Name string
+}
+
+func (v DCOSVariant) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("\"%s\"", v.Name)), nil
+}
+
+func (v DCOSVariant) String() string {
+	return v.Name
+}
+
+// Set selects the variant. It only checks that the variant exists; what the
+// variant needs is set up by InitVariant.
+func (v *DCOSVariant) Set(variant string) error {
+	variantsMu.Lock()
+	_, ok := variants[variant]
+	variantsMu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("unknown variant '%s'. Only '%s' are allowed", variant, strings.Join(variantNames(), "' or '"))
+	}
+	v.Name = variant
+	return nil
+}
+
+// InitVariant sets up what is specific to the selected variant. It is called
+// by ParseArgsReturnConfig once flags and the JSON config are loaded, and
+// needs to be called by anyone building a Config otherwise.
+func (c *Config) InitVariant() error {
+	variantsMu.Lock()
+	init, ok := variants[c.DCOSVariant.Name]
+	variantsMu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("unknown variant '%s'. Only '%s' are allowed", c.DCOSVariant.Name, strings.Join(variantNames(), "' or '"))
+	}
+	if init == nil {
+		return nil
+	}
+	return init(c)
+}
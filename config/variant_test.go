@@ -0,0 +1,96 @@
+// +build unit
+
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDCOSVariantSet(t *testing.T) {
+	var v DCOSVariant
+	if err := v.Set("enterprise"); err != nil || v.Name != "enterprise" {
+		t.Error("Expected enterprise variant, got", v, err)
+	}
+	if DefaultConfig().DCOSVariant.Name != "open" {
+		t.Error("Expected selecting a variant to leave the defaults alone, got", DefaultConfig().DCOSVariant)
+	}
+	if err := v.Set("foo"); err == nil || !strings.Contains(err.Error(), "'enterprise' or 'open'") {
+		t.Error("Expected error listing known variants, got", err)
+	}
+}
+
+func TestInitVariant(t *testing.T) {
+	var initialized *Config
+	RegisterVariant("test-variant", func(c *Config) error {
+		initialized = c
+		c.ExtraHeaders = map[string]string{"X-Variant": "test"}
+		return nil
+	})
+	RegisterVariant("test-broken", func(c *Config) error {
+		return errors.New("broken")
+	})
+	defer func() {
+		delete(variants, "test-variant")
+		delete(variants, "test-broken")
+	}()
+
+	c := DefaultConfig()
+	if err := c.InitVariant(); err != nil {
+		t.Error("Expected nil error for open variant, got", err)
+	}
+
+	c.DCOSVariant = DCOSVariant{"test-variant"}
+	if err := c.InitVariant(); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if initialized != &c || c.ExtraHeaders["X-Variant"] != "test" {
+		t.Error("Expected variant init to set up the config, got", c.ExtraHeaders)
+	}
+
+	c.DCOSVariant = DCOSVariant{"test-broken"}
+	if err := c.InitVariant(); err == nil || err.Error() != "broken" {
+		t.Error("Expected error from variant init, got", err)
+	}
+
+	c.DCOSVariant = DCOSVariant{"foo"}
+	if err := c.InitVariant(); err == nil {
+		t.Error("Expected error for unknown variant, got nil")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic registering a variant twice")
+		}
+	}()
+	RegisterVariant("open", nil)
+}
+
+func TestParseArgsEnterpriseWithoutCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signal-variant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	clusterID := filepath.Join(dir, "cluster-id")
+	ioutil.WriteFile(clusterID, []byte("12345"), 0600)
+	configPath := filepath.Join(dir, "config.json")
+	ioutil.WriteFile(configPath, []byte(`{"service_account_path": "`+filepath.Join(dir, "missing.json")+`"}`), 0600)
+
+	c, errs := ParseArgsReturnConfig([]string{
+		"-dcos-variant", "enterprise",
+		"-cluster-id-path", clusterID,
+		"-c", configPath,
+	})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "missing.json") {
+		t.Error("Expected error naming the configured service account, got", errs)
+	}
+	if c.DCOSVariant.Name != "enterprise" || c.Auth != nil {
+		t.Error("Expected enterprise variant without auth, got", c.DCOSVariant, c.Auth)
+	}
+}
@@ -0,0 +1,289 @@
+package signal
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/dcos/dcos-signal/config"
+	"gopkg.in/segmentio/analytics-go.v2"
+)
+
+// AgentsReport is the Mesos master /master/slaves response
+type AgentsReport struct {
+	Agents []Agent `json:"slaves"`
+}
+
+// Agent defines the fields of an agent in the /master/slaves response
+type Agent struct {
+	ID                string                    `json:"id"`
+	Active            bool                      `json:"active"`
+	Deactivated       bool                      `json:"deactivated"`
+	Version           string                    `json:"version"`
+	Attributes        map[string]interface{}    `json:"attributes"`
+	Resources         MesosResources            `json:"resources"`
+	ReservedResources map[string]MesosResources `json:"reserved_resources"`
+	// ReservedFull lists the reserved resources of each role one by one,
+	// including whether they are reserved statically or dynamically.
+	ReservedFull map[string][]MesosResource `json:"reserved_resources_full"`
+	Domain       *struct {
+		FaultDomain *struct {
+			Region struct {
+				Name string `json:"name"`
+			} `json:"region"`
+			Zone struct {
+				Name string `json:"name"`
+			} `json:"zone"`
+		} `json:"fault_domain"`
+	} `json:"domain"`
+	DrainInfo *struct {
+		State string `json:"state"`
+	} `json:"drain_info"`
+}
+
+// RoleReservations is an entry of the reserved_by_role property.
+type RoleReservations struct {
+	Static  MesosResources `json:"static"`
+	Dynamic MesosResources `json:"dynamic"`
+}
+
+// dcosPublicRole is the role DC/OS reserves the resources of public agents for.
+const dcosPublicRole = "slave_public"
+
+// public reports whether the agent is a DC/OS public agent.
+func (a Agent) public() bool {
+	if _, ok := a.ReservedResources[dcosPublicRole]; ok {
+		return true
+	}
+	return fmt.Sprint(a.Attributes["public_ip"]) == "true"
+}
+
+// region and zone return the fault domain of the agent, "none" if it has none.
+func (a Agent) region() string {
+	if a.Domain == nil || a.Domain.FaultDomain == nil {
+		return "none"
+	}
+	return a.Domain.FaultDomain.Region.Name
+}
+
+func (a Agent) zone() string {
+	if a.Domain == nil || a.Domain.FaultDomain == nil {
+		return "none"
+	}
+	return a.Domain.FaultDomain.Region.Name + "/" + a.Domain.FaultDomain.Zone.Name
+}
+
+// state returns the state of the agent for agents_by_state: "draining" and
+// "drained" for agents being drained for maintenance, "deactivated" for agents
+// deactivated by an operator, "inactive" for agents that are disconnected.
+func (a Agent) state() string {
+	switch {
+	case a.DrainInfo != nil && a.DrainInfo.State == "DRAINING":
+		return "draining"
+	case a.DrainInfo != nil && a.DrainInfo.State == "DRAINED":
+		return "drained"
+	case a.Deactivated:
+		return "deactivated"
+	case !a.Active:
+		return "inactive"
+	}
+	return "active"
+}
+
+// Agent size buckets by cpus, upper bounds inclusive.
+var agentSizeBuckets = []struct {
+	name    string
+	maxCPUs float64
+}{
+	{"small", 4},
+	{"medium", 16},
+	{"large", 64},
+}
+
+// size returns the size bucket of the agent, "xlarge" above the largest bucket.
+func (a Agent) size() string {
+	for _, b := range agentSizeBuckets {
+		if a.Resources.CPUs <= b.maxCPUs {
+			return b.name
+		}
+	}
+	return "xlarge"
+}
+
+// AgentSize is an entry of the agents_by_size property: the number of agents
+// in a size bucket and their total resources.
+type AgentSize struct {
+	Agents int `json:"agents"`
+	MesosResources
+}
+
+// AgentAttribute is an entry of the agents_by_attribute property. Attribute
+// values are only counted, not sent, since they often name customer hosts or
+// racks.
+type AgentAttribute struct {
+	Agents         int `json:"agents"`
+	DistinctValues int `json:"distinct_values"`
+}
+
+// Agents implements a Reporter for the agents registered with the Mesos master
+type Agents struct {
+	Report    *AgentsReport
+	Name      string
+	Endpoints []string
+	Method    string
+	Headers   map[string]string
+	Track     *analytics.Track
+	Error     []ReportError
+}
+
+func (a *Agents) GetName() string {
+	return a.Name
+}
+
+func (a *Agents) SetReport(body []byte) error {
+	if err := json.Unmarshal(body, &a.Report); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (a *Agents) GetReport() interface{} {
+	return a.Report
+}
+
+func (a *Agents) AddHeaders(head map[string]string) {
+	for k, v := range head {
+		a.Headers[k] = v
+	}
+}
+
+func (a *Agents) GetHeaders() map[string]string {
+	return a.Headers
+}
+
+// EndpointCount returns the number of endpoints Agents needs.
+func (a *Agents) EndpointCount() int {
+	return 1
+}
+
+func (a *Agents) GetEndpoints() []string {
+	return a.Endpoints
+}
+
+func (a *Agents) GetMethod() string {
+	return a.Method
+}
+
+func (a *Agents) GetError() []ReportError {
+	return a.Error
+}
+
+func (a *Agents) AppendError(err ReportError) {
+	a.Error = append(a.Error, err)
+}
+
+// TrackEvent returns the event of the Agents track.
+func (a *Agents) TrackEvent(config.Config) string {
+	return "agents_track"
+}
+
+func (a *Agents) SetTrack(c config.Config) error {
+	if a.Report == nil {
+		return fmt.Errorf("%s report is nil, bailing out", a.Name)
+	}
+
+	var (
+		public, gpuAgents int
+		gpuTotal          float64
+		byState           = map[string]int{"active": 0, "inactive": 0, "deactivated": 0, "draining": 0, "drained": 0}
+		byRegion          = make(map[string]int)
+		byZone            = make(map[string]int)
+		byVersion         = make(map[string]int)
+		bySize            = make(map[string]*AgentSize)
+		byAttribute       = make(map[string]*AgentAttribute)
+		attributeValues   = make(map[string]map[string]bool)
+		static, dynamic   MesosResources
+		byRole            = make(map[string]*RoleReservations)
+	)
+	for _, b := range agentSizeBuckets {
+		bySize[b.name] = &AgentSize{}
+	}
+	bySize["xlarge"] = &AgentSize{}
+
+	for _, agent := range a.Report.Agents {
+		if agent.public() {
+			public++
+		}
+		if agent.Resources.GPUs > 0 {
+			gpuAgents++
+			gpuTotal += agent.Resources.GPUs
+		}
+		byState[agent.state()]++
+		byRegion[agent.region()]++
+		byZone[agent.zone()]++
+		byVersion[agent.Version]++
+
+		size := bySize[agent.size()]
+		size.Agents++
+		size.CPUs += agent.Resources.CPUs
+		size.Mem += agent.Resources.Mem
+		size.Disk += agent.Resources.Disk
+		size.GPUs += agent.Resources.GPUs
+
+		for name, value := range agent.Attributes {
+			if byAttribute[name] == nil {
+				byAttribute[name] = &AgentAttribute{}
+				attributeValues[name] = make(map[string]bool)
+			}
+			byAttribute[name].Agents++
+			attributeValues[name][fmt.Sprint(value)] = true
+		}
+
+		for role, resources := range agent.ReservedFull {
+			if byRole[role] == nil {
+				byRole[role] = &RoleReservations{}
+			}
+			for _, res := range resources {
+				if res.dynamic() {
+					dynamic.add(res.Name, res.value())
+					byRole[role].Dynamic.add(res.Name, res.value())
+				} else {
+					static.add(res.Name, res.value())
+					byRole[role].Static.add(res.Name, res.value())
+				}
+			}
+		}
+	}
+	for name, values := range attributeValues {
+		byAttribute[name].DistinctValues = len(values)
+	}
+
+	properties := trackProperties(c, map[string]interface{}{
+		"agent_count":         len(a.Report.Agents),
+		"public_agents":       public,
+		"private_agents":      len(a.Report.Agents) - public,
+		"gpu_agents":          gpuAgents,
+		"gpu_total":           gpuTotal,
+		"agents_by_state":     byState,
+		"agents_by_region":    byRegion,
+		"agents_by_zone":      byZone,
+		"agents_by_size":      bySize,
+		"agents_by_attribute": byAttribute,
+		"agents_by_version":   byVersion,
+		"version_count":       len(byVersion),
+		"reserved_static":     static,
+		"reserved_dynamic":    dynamic,
+		"reserved_by_role":    byRole,
+	})
+
+	a.Track = &analytics.Track{
+		Event:       a.TrackEvent(c),
+		UserId:      c.CustomerKey,
+		AnonymousId: c.ClusterID,
+		Properties:  properties,
+	}
+	return nil
+}
+
+func (a *Agents) GetTrack() *analytics.Track {
+	return a.Track
+}
@@ -0,0 +1,127 @@
+// +build unit
+
+package signal
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/dcos/dcos-signal/config"
+)
+
+const mockMesosAgents = `
+{
+  "slaves": [
+    {"id": "a1", "active": true, "version": "1.9.0",
+     "attributes": {"rack": "r1", "public_ip": "true"},
+     "resources": {"cpus": 4, "mem": 15000, "disk": 100000, "gpus": 0, "ports": "[1-21, 23-5050]"},
+     "reserved_resources": {"slave_public": {"cpus": 4, "mem": 15000}},
+     "reserved_resources_full": {"slave_public": [
+       {"name": "cpus", "type": "SCALAR", "scalar": {"value": 4}, "role": "slave_public"},
+       {"name": "mem", "type": "SCALAR", "scalar": {"value": 15000},
+        "reservations": [{"type": "STATIC", "role": "slave_public"}]}]},
+     "domain": {"fault_domain": {"region": {"name": "us-east-1"}, "zone": {"name": "us-east-1a"}}}},
+    {"id": "a2", "active": true, "version": "1.9.0",
+     "attributes": {"rack": "r2"},
+     "resources": {"cpus": 32, "mem": 120000, "disk": 500000, "gpus": 4},
+     "domain": {"fault_domain": {"region": {"name": "us-east-1"}, "zone": {"name": "us-east-1b"}}},
+     "reserved_resources_full": {"dev": [
+       {"name": "cpus", "type": "SCALAR", "scalar": {"value": 2},
+        "reservations": [{"type": "STATIC", "role": "dev"}, {"type": "DYNAMIC", "role": "dev"}]},
+       {"name": "disk", "type": "SCALAR", "scalar": {"value": 1000}, "role": "dev",
+        "reservation": {"principal": "ops"}}]},
+     "drain_info": {"state": "DRAINING"}},
+    {"id": "a3", "active": false, "version": "1.8.1",
+     "attributes": {"rack": "r2", "gpu": 1},
+     "resources": {"cpus": 128, "mem": 500000, "disk": 1000000, "gpus": 0}}
+  ],
+  "recovered_slaves": []
+}`
+
+func TestAgentsTrack(t *testing.T) {
+	c := config.DefaultConfig()
+	c.MesosURLs = []string{fmt.Sprintf("%s/frameworks", server.URL), fmt.Sprintf("%s/metrics/snapshot", server.URL)}
+
+	agents := findReporter(t, c, "agents")
+	if agents == nil {
+		t.Fatal("Expected agents reporter when mesos_urls are set")
+	}
+	if endpoints := agents.GetEndpoints(); endpoints[0] != server.URL+"/master/slaves" {
+		t.Error("Expected agents to be read from the mesos master, got", endpoints)
+	}
+
+	if err := runner(context.Background(), []Reporter{agents}, c); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if errs := agents.GetError(); len(errs) != 0 {
+		t.Fatal("Expected no errors, got", errs)
+	}
+
+	track := agents.GetTrack()
+	if track.Event != "agents_track" {
+		t.Error("Expected event agents_track, got", track.Event)
+	}
+	for key, expected := range map[string]interface{}{
+		"agent_count":    3,
+		"public_agents":  1,
+		"private_agents": 2,
+		"gpu_agents":     1,
+		"gpu_total":      4.0,
+		"version_count":  2,
+	} {
+		if track.Properties[key] != expected {
+			t.Errorf("Expected %s to be %v, got %v", key, expected, track.Properties[key])
+		}
+	}
+
+	byState := track.Properties["agents_by_state"].(map[string]int)
+	if byState["active"] != 1 || byState["draining"] != 1 || byState["inactive"] != 1 {
+		t.Error("Expected 1 active, 1 draining and 1 inactive agent, got", byState)
+	}
+
+	byZone := track.Properties["agents_by_zone"].(map[string]int)
+	if byZone["us-east-1/us-east-1a"] != 1 || byZone["us-east-1/us-east-1b"] != 1 || byZone["none"] != 1 {
+		t.Error("Expected one agent per zone and one without fault domain, got", byZone)
+	}
+	byRegion := track.Properties["agents_by_region"].(map[string]int)
+	if byRegion["us-east-1"] != 2 || byRegion["none"] != 1 {
+		t.Error("Expected 2 agents in us-east-1 and one without fault domain, got", byRegion)
+	}
+
+	bySize := track.Properties["agents_by_size"].(map[string]*AgentSize)
+	if bySize["small"].Agents != 1 || bySize["large"].Agents != 1 || bySize["xlarge"].Agents != 1 || bySize["medium"].Agents != 0 {
+		t.Error("Expected one small, large and xlarge agent each")
+	}
+	if bySize["large"].GPUs != 4 || bySize["xlarge"].CPUs != 128 {
+		t.Error("Expected resource totals per size bucket, got", *bySize["large"], *bySize["xlarge"])
+	}
+
+	byAttribute := track.Properties["agents_by_attribute"].(map[string]*AgentAttribute)
+	if rack := byAttribute["rack"]; rack == nil || rack.Agents != 3 || rack.DistinctValues != 2 {
+		t.Error("Expected rack on 3 agents with 2 distinct values, got", rack)
+	}
+
+	byVersion := track.Properties["agents_by_version"].(map[string]int)
+	if byVersion["1.9.0"] != 2 || byVersion["1.8.1"] != 1 {
+		t.Error("Expected 2 agents on 1.9.0 and 1 on 1.8.1, got", byVersion)
+	}
+
+	if static := track.Properties["reserved_static"]; static != (MesosResources{CPUs: 4, Mem: 15000}) {
+		t.Error("Expected 4 cpus and 15000 mem reserved statically, got", static)
+	}
+	if dynamic := track.Properties["reserved_dynamic"]; dynamic != (MesosResources{CPUs: 2, Disk: 1000}) {
+		t.Error("Expected 2 cpus and 1000 disk reserved dynamically, got", dynamic)
+	}
+
+	byRole := track.Properties["reserved_by_role"].(map[string]*RoleReservations)
+	if dev := byRole["dev"]; dev == nil || dev.Dynamic.CPUs != 2 || dev.Static != (MesosResources{}) {
+		t.Error("Expected 2 cpus reserved dynamically for dev, got", dev)
+	}
+}
+
+func TestAgentsOptional(t *testing.T) {
+	if findReporter(t, config.DefaultConfig(), "agents") != nil {
+		t.Error("Expected no agents reporter without mesos_urls")
+	}
+}
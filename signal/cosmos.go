@@ -3,6 +3,9 @@ package signal
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/dcos/dcos-signal/config"
@@ -39,6 +42,91 @@ func (c CosmosReport) String() string {
 	return strings.Join(pkgs, ", ")
 }
 
+// CosmosRepository defines a repository in the /package/repository/list
+// response
+type CosmosRepository struct {
+	Name string `json:"name"`
+	URI  string `json:"uri"`
+}
+
+// kind returns "universe" for the Mesosphere Universe, "local" for a Universe
+// served from within the cluster and "custom" for any other repository.
+func (r CosmosRepository) kind() string {
+	u, err := url.Parse(r.URI)
+	if err != nil {
+		return "custom"
+	}
+	host := u.Hostname()
+	switch {
+	case host == "universe.mesosphere.com",
+		host == "downloads.mesosphere.com" && strings.HasPrefix(u.Path, "/universe/"):
+		return "universe"
+	case host == "master.mesos", host == "localhost", host == "127.0.0.1",
+		strings.HasSuffix(host, ".thisdcos.directory"):
+		return "local"
+	}
+	return "custom"
+}
+
+// CosmosPackageVersion is an entry of the package_versions property: an
+// installed version of a package compared to the versions its repositories
+// offer.
+type CosmosPackageVersion struct {
+	Name           string `json:"name"`
+	Version        string `json:"version"`
+	LatestVersion  string `json:"latest_version"`
+	VersionsBehind int    `json:"versions_behind"`
+	Outdated       bool   `json:"outdated"`
+}
+
+// CosmosPackageSummary is an entry of the package_summary property: how often
+// a package is installed and in which versions.
+type CosmosPackageSummary struct {
+	Instances   int      `json:"instances"`
+	Versions    []string `json:"versions"`
+	VersionSkew bool     `json:"version_skew"`
+}
+
+// packageSummary summarizes the installed packages by name.
+func (c CosmosReport) packageSummary() map[string]*CosmosPackageSummary {
+	summary := make(map[string]*CosmosPackageSummary)
+	for _, pkg := range c.Packages {
+		def := pkg.PackageInformation.PackageDefinition
+		if def.Name == "" {
+			continue
+		}
+		s, ok := summary[def.Name]
+		if !ok {
+			s = &CosmosPackageSummary{Versions: []string{}}
+			summary[def.Name] = s
+		}
+		s.Instances++
+		if !containsString(s.Versions, def.Version) {
+			s.Versions = append(s.Versions, def.Version)
+		}
+	}
+	for _, s := range summary {
+		sort.Strings(s.Versions)
+		s.VersionSkew = len(s.Versions) > 1
+	}
+	return summary
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Keys of the follow-up requests of the cosmos reporter
+const (
+	cosmosRepositoriesKey = "repositories"
+	cosmosVersionsKey     = "versions:"
+)
+
 // Cosmos implements a Reporter for the cosmos service
 type Cosmos struct {
 	Report    *CosmosReport
@@ -46,74 +134,237 @@ type Cosmos struct {
 	Method    string
 	Headers   map[string]string
 	Track     *analytics.Track
-	Error     []string
+	Error     []ReportError
 	Name      string
+
+	// Repositories and the release of every version of the installed
+	// packages, gathered by follow-up requests
+	Repositories []CosmosRepository
+	Versions     map[string]map[string]int
 }
 
-func (c *Cosmos) getName() string {
+func (c *Cosmos) GetName() string {
 	return c.Name
 }
 
-func (c *Cosmos) setReport(body []byte) error {
+func (c *Cosmos) SetReport(body []byte) error {
 	if err := json.Unmarshal(body, &c.Report); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (c *Cosmos) getReport() interface{} {
+// FollowUps lists the repositories and the versions of every installed
+// package, from the cosmos that serves the package list.
+func (c *Cosmos) FollowUps() []Request {
+	if len(c.Endpoints) == 0 || !strings.HasSuffix(c.Endpoints[0], "/package/list") {
+		return nil
+	}
+	base := strings.TrimSuffix(c.Endpoints[0], "/package/list")
+
+	requests := []Request{
+		c.request(cosmosRepositoriesKey, base+"/package/repository/list", "repository.list", "{}"),
+	}
+	if c.Report == nil {
+		return requests
+	}
+
+	names := make(map[string]bool)
+	for _, pkg := range c.Report.Packages {
+		if name := pkg.PackageInformation.PackageDefinition.Name; name != "" {
+			names[name] = true
+		}
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	for _, name := range sorted {
+		body, _ := json.Marshal(map[string]interface{}{
+			"packageName":            name,
+			"includePackageVersions": true,
+		})
+		requests = append(requests, c.request(cosmosVersionsKey+name, base+"/package/list-versions", "list-versions", string(body)))
+	}
+	return requests
+}
+
+// request returns a request to a cosmos API, which needs the content type and
+// accept headers of that API rather than those of the package list.
+func (c *Cosmos) request(key, endpoint, api, body string) Request {
+	headers := make(map[string]string)
+	for k, v := range c.Headers {
+		headers[k] = v
+	}
+	headers["content-type"] = fmt.Sprintf("application/vnd.dcos.package.%s-request+json;charset=utf-8;version=v1", api)
+	headers["accept"] = fmt.Sprintf("application/vnd.dcos.package.%s-response+json;charset=utf-8;version=v1", api)
+	return Request{
+		Key:      key,
+		Endpoint: endpoint,
+		Method:   "POST",
+		Headers:  headers,
+		Body:     body,
+	}
+}
+
+func (c *Cosmos) SetFollowUpReport(req Request, body []byte) error {
+	switch {
+	case req.Key == cosmosRepositoriesKey:
+		var list struct {
+			Repositories []CosmosRepository `json:"repositories"`
+		}
+		if err := json.Unmarshal(body, &list); err != nil {
+			return err
+		}
+		c.Repositories = append([]CosmosRepository{}, list.Repositories...)
+	case strings.HasPrefix(req.Key, cosmosVersionsKey):
+		var list struct {
+			Results map[string]string `json:"results"`
+		}
+		if err := json.Unmarshal(body, &list); err != nil {
+			return err
+		}
+		releases := make(map[string]int)
+		for version, release := range list.Results {
+			r, err := strconv.Atoi(release)
+			if err != nil {
+				return fmt.Errorf("invalid release %q of version %s", release, version)
+			}
+			releases[version] = r
+		}
+		if c.Versions == nil {
+			c.Versions = make(map[string]map[string]int)
+		}
+		c.Versions[strings.TrimPrefix(req.Key, cosmosVersionsKey)] = releases
+	default:
+		return fmt.Errorf("unknown follow-up %s", req.Key)
+	}
+	return nil
+}
+
+// packageVersions compares every installed version of a package to the
+// versions its repositories offer. Versions that cannot be compared, because
+// the versions of the package are not known or the installed one is not
+// offered anymore, are left out.
+func (c *Cosmos) packageVersions() []CosmosPackageVersion {
+	var (
+		versions []CosmosPackageVersion
+		seen     = make(map[string]bool)
+	)
+	for _, pkg := range c.Report.Packages {
+		def := pkg.PackageInformation.PackageDefinition
+		installed, ok := c.Versions[def.Name][def.Version]
+		if !ok || seen[pkg.String()] {
+			continue
+		}
+		seen[pkg.String()] = true
+
+		v := CosmosPackageVersion{Name: def.Name, Version: def.Version}
+		latest := -1
+		for version, release := range c.Versions[def.Name] {
+			if release > latest {
+				latest = release
+				v.LatestVersion = version
+			}
+			if release > installed {
+				v.VersionsBehind++
+			}
+		}
+		v.Outdated = v.VersionsBehind > 0
+		versions = append(versions, v)
+	}
+	return versions
+}
+
+func (c *Cosmos) GetReport() interface{} {
 	return c.Report
 }
 
-func (c *Cosmos) addHeaders(head map[string]string) {
+func (c *Cosmos) AddHeaders(head map[string]string) {
 	for k, v := range head {
 		c.Headers[k] = v
 	}
 }
 
-func (c *Cosmos) getHeaders() map[string]string {
+func (c *Cosmos) GetHeaders() map[string]string {
 	return c.Headers
 }
 
-func (c *Cosmos) getEndpoints() []string {
-	if len(c.Endpoints) != 1 {
-		log.Errorf("Cosmos needs 1 endpoint, got %d", len(c.Endpoints))
-	}
+// EndpointCount returns the number of endpoints Cosmos needs.
+func (c *Cosmos) EndpointCount() int {
+	return 1
+}
+
+func (c *Cosmos) GetEndpoints() []string {
 	return c.Endpoints
 }
 
-func (c *Cosmos) getMethod() string {
+func (c *Cosmos) GetMethod() string {
 	return c.Method
 }
 
-func (c *Cosmos) getError() []string {
+func (c *Cosmos) GetError() []ReportError {
 	return c.Error
 }
 
-func (c *Cosmos) appendError(err string) {
+func (c *Cosmos) AppendError(err ReportError) {
 	c.Error = append(c.Error, err)
 }
 
-func (c *Cosmos) setTrack(config config.Config) error {
+// TrackEvent returns the event of the Cosmos track.
+func (c *Cosmos) TrackEvent(config.Config) string {
+	return "package_list"
+}
+
+func (c *Cosmos) SetTrack(config config.Config) error {
 	if c.Report == nil {
 		return fmt.Errorf("%s report is nil, bailing out.", c.Name)
 	}
 
 	log.Infof("Installed cosmos packages: %s", c.Report)
-	properties := map[string]interface{}{
-		"package_list":       c.Report.Packages,
-		"source":             "cluster",
-		"customerKey":        config.CustomerKey,
-		"environmentVersion": config.DCOSVersion,
-		"clusterId":          config.ClusterID,
-		"licenseId":          config.LicenseID,
-		"variant":            config.DCOSVariant,
-		"platform":           config.GenPlatform,
-		"provider":           config.GenProvider,
+	summary := c.Report.packageSummary()
+	skewed := 0
+	for _, s := range summary {
+		if s.VersionSkew {
+			skewed++
+		}
+	}
+	properties := trackProperties(config, map[string]interface{}{
+		"package_count":     len(summary),
+		"package_instances": len(c.Report.Packages),
+		"package_summary":   summary,
+		"skewed_packages":   skewed,
+	})
+	// The list has an entry per installed instance, which adds up on large
+	// clusters.
+	if config.SendPackageList {
+		properties["package_list"] = c.Report.Packages
+	}
+
+	if c.Repositories != nil {
+		byKind := map[string]int{"universe": 0, "local": 0, "custom": 0}
+		for _, repo := range c.Repositories {
+			byKind[repo.kind()]++
+		}
+		properties["repository_count"] = len(c.Repositories)
+		properties["repositories_by_kind"] = byKind
+	}
+	if c.Versions != nil {
+		versions := c.packageVersions()
+		outdated := 0
+		for _, v := range versions {
+			if v.Outdated {
+				outdated++
+			}
+		}
+		properties["package_versions"] = versions
+		properties["outdated_packages"] = outdated
 	}
 
 	c.Track = &analytics.Track{
-		Event:       "package_list",
+		Event:       c.TrackEvent(config),
 		UserId:      config.CustomerKey,
 		AnonymousId: config.ClusterID,
 		Properties:  properties,
@@ -121,13 +372,6 @@ func (c *Cosmos) setTrack(config config.Config) error {
 	return nil
 }
 
-func (c *Cosmos) getTrack() *analytics.Track {
+func (c *Cosmos) GetTrack() *analytics.Track {
 	return c.Track
 }
-
-func (c *Cosmos) sendTrack(config config.Config) error {
-	ac := CreateSegmentClient(config.SegmentKey, config.FlagVerbose)
-	defer ac.Close()
-	err := ac.Track(c.Track)
-	return err
-}
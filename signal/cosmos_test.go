@@ -3,7 +3,10 @@
 package signal
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"strings"
 	"testing"
 
 	"encoding/json"
@@ -19,6 +22,49 @@ var (
 	}
 )
 
+const (
+	mockCosmosRepositories = `
+{
+  "repositories": [
+    {"name": "Universe", "uri": "https://universe.mesosphere.com/repo"},
+    {"name": "Local", "uri": "http://master.mesos:8082/repo"},
+    {"name": "Acme", "uri": "https://repo.example.com/universe.json"}
+  ]
+}`
+	mockCosmosInstalled = `
+{
+  "packages": [
+    {"appId": "/kafka", "packageInformation": {"packageDefinition": {"name": "kafka", "version": "2.0.0"}}},
+    {"appId": "/kafka-2", "packageInformation": {"packageDefinition": {"name": "kafka", "version": "2.0.0"}}},
+    {"appId": "/kafka-3", "packageInformation": {"packageDefinition": {"name": "kafka", "version": "2.2.0"}}},
+    {"appId": "/cassandra", "packageInformation": {"packageDefinition": {"name": "cassandra", "version": "1.0.0"}}},
+    {"appId": "/custom", "packageInformation": {"packageDefinition": {"name": "custom", "version": "0.1.0"}}}
+  ]
+}`
+)
+
+// mockCosmosVersionsHandler lists the versions of kafka and cassandra, and
+// fails for any other package as cosmos does for unknown packages.
+func mockCosmosVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PackageName string `json:"packageName"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	if !strings.HasPrefix(r.Header.Get("content-type"), "application/vnd.dcos.package.list-versions-request+json") {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	switch req.PackageName {
+	case "kafka":
+		fmt.Fprint(w, `{"results": {"2.0.0": "0", "2.1.0": "1", "2.2.0": "2", "2.3.0": "3"}}`)
+	case "cassandra":
+		fmt.Fprint(w, `{"results": {"1.0.0": "0"}}`)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
 func TestCosmosTrack(t *testing.T) {
 	c := config.DefaultConfig()
 	c.CustomerKey = "12345"
@@ -30,18 +76,18 @@ func TestCosmosTrack(t *testing.T) {
 	c.DCOSVariant = config.DCOSVariant{"test_variant"}
 
 	for _, endpoint := range testCosmos.Endpoints {
-		pullErr := PullReport(endpoint, &testCosmos, c)
+		pullErr := PullReport(context.Background(), endpoint, &testCosmos, c)
 		if pullErr != nil {
 			t.Error("Expected no errors pulling report from test server, got", pullErr)
 		}
 	}
 
-	setupErr := testCosmos.setTrack(c)
+	setupErr := testCosmos.SetTrack(c)
 	if setupErr != nil {
 		t.Error("Expected no errors setting up track, got", setupErr)
 	}
 
-	actualSegmentTrack := testCosmos.getTrack()
+	actualSegmentTrack := testCosmos.GetTrack()
 	if actualSegmentTrack.Event != "package_list" {
 		t.Error("Expected actualSegmentTrack.Event to be 'package_list', got ", actualSegmentTrack.Event)
 	}
@@ -128,3 +174,107 @@ func TestCosmosStringer(t *testing.T) {
 		t.Fatalf("Expect %s. Got %s", expectedLine, report)
 	}
 }
+
+func TestCosmosOutdatedPackages(t *testing.T) {
+	cosmos := &Cosmos{
+		Name:      "cosmos",
+		Endpoints: []string{fmt.Sprintf("%s/outdated/package/list", server.URL)},
+		Method:    "POST",
+		Headers:   map[string]string{"Authorization": "token=abc"},
+	}
+	if err := runner(context.Background(), []Reporter{cosmos}, config.DefaultConfig()); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	// custom is not offered by any repository.
+	if errs := cosmos.GetError(); len(errs) != 1 || errs[0].StatusCode != http.StatusBadRequest {
+		t.Error("Expected a single 400 error for the unknown package, got", errs)
+	}
+
+	track := cosmos.GetTrack()
+	if track.Properties["repository_count"] != 3 {
+		t.Error("Expected 3 repositories, got", track.Properties["repository_count"])
+	}
+	byKind := track.Properties["repositories_by_kind"].(map[string]int)
+	if byKind["universe"] != 1 || byKind["local"] != 1 || byKind["custom"] != 1 {
+		t.Error("Expected one universe, local and custom repository each, got", byKind)
+	}
+
+	versions := track.Properties["package_versions"].([]CosmosPackageVersion)
+	expected := []CosmosPackageVersion{
+		{Name: "kafka", Version: "2.0.0", LatestVersion: "2.3.0", VersionsBehind: 3, Outdated: true},
+		{Name: "kafka", Version: "2.2.0", LatestVersion: "2.3.0", VersionsBehind: 1, Outdated: true},
+		{Name: "cassandra", Version: "1.0.0", LatestVersion: "1.0.0", VersionsBehind: 0, Outdated: false},
+	}
+	if len(versions) != len(expected) {
+		t.Fatal("Expected one entry per installed version, got", versions)
+	}
+	for i := range expected {
+		if versions[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], versions[i])
+		}
+	}
+	if track.Properties["outdated_packages"] != 2 {
+		t.Error("Expected 2 outdated packages, got", track.Properties["outdated_packages"])
+	}
+}
+
+func TestCosmosFollowUpHeaders(t *testing.T) {
+	cosmos := &Cosmos{
+		Name:      "cosmos",
+		Endpoints: []string{"http://localhost:7070/package/list"},
+		Headers: map[string]string{
+			"content-type":  "application/vnd.dcos.package.list-request+json;charset=utf-8;version=v1",
+			"Authorization": "token=abc",
+		},
+	}
+	requests := cosmos.FollowUps()
+	if len(requests) != 1 || requests[0].Endpoint != "http://localhost:7070/package/repository/list" {
+		t.Fatal("Expected only the repository list without a package list, got", requests)
+	}
+	if requests[0].Headers["Authorization"] != "token=abc" {
+		t.Error("Expected follow-ups to carry the reporter headers, got", requests[0].Headers)
+	}
+	if !strings.HasPrefix(requests[0].Headers["content-type"], "application/vnd.dcos.package.repository.list-request+json") {
+		t.Error("Expected repository list content type, got", requests[0].Headers["content-type"])
+	}
+	if cosmos.Headers["content-type"] != "application/vnd.dcos.package.list-request+json;charset=utf-8;version=v1" {
+		t.Error("Expected reporter headers to stay untouched, got", cosmos.Headers)
+	}
+}
+
+func TestCosmosPackageSummary(t *testing.T) {
+	c := config.DefaultConfig()
+	c.SendPackageList = false
+	cosmos := &Cosmos{
+		Name:      "cosmos",
+		Endpoints: []string{fmt.Sprintf("%s/outdated/package/list", server.URL)},
+		Method:    "POST",
+	}
+	if err := runner(context.Background(), []Reporter{cosmos}, c); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	track := cosmos.GetTrack()
+	if _, ok := track.Properties["package_list"]; ok {
+		t.Error("Expected no package_list with send_package_list off")
+	}
+	for key, expected := range map[string]interface{}{
+		"package_count":     3,
+		"package_instances": 5,
+		"skewed_packages":   1,
+	} {
+		if track.Properties[key] != expected {
+			t.Errorf("Expected %s to be %v, got %v", key, expected, track.Properties[key])
+		}
+	}
+
+	summary := track.Properties["package_summary"].(map[string]*CosmosPackageSummary)
+	kafka := summary["kafka"]
+	if kafka == nil || kafka.Instances != 3 || !kafka.VersionSkew || strings.Join(kafka.Versions, ",") != "2.0.0,2.2.0" {
+		t.Error("Expected 3 kafka instances in versions 2.0.0 and 2.2.0, got", kafka)
+	}
+	if cassandra := summary["cassandra"]; cassandra == nil || cassandra.Instances != 1 || cassandra.VersionSkew {
+		t.Error("Expected a single cassandra instance without skew, got", cassandra)
+	}
+}
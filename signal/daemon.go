@@ -0,0 +1,140 @@
+package signal
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	ossignal "os/signal"
+	"syscall"
+	"time"
+
+	"github.com/dcos/dcos-signal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// scheduler keeps track of when each reporter last ran, so reporters can run
+// at their own cadence while sharing a single daemon loop.
+type scheduler struct {
+	interval  time.Duration
+	jitter    time.Duration
+	intervals map[string]time.Duration
+	lastRun   map[string]time.Time
+}
+
+func newScheduler(c config.Config) *scheduler {
+	s := &scheduler{
+		interval:  c.Interval,
+		jitter:    c.IntervalJitter,
+		intervals: make(map[string]time.Duration),
+		lastRun:   make(map[string]time.Time),
+	}
+	for name, d := range c.ReporterIntervals {
+		s.intervals[name] = d.Duration
+	}
+	return s
+}
+
+// intervalFor returns the cadence of the reporter with the given name.
+func (s *scheduler) intervalFor(name string) time.Duration {
+	if d, ok := s.intervals[name]; ok && d > 0 {
+		return d
+	}
+	return s.interval
+}
+
+// due returns the reporters that never ran or whose interval has passed.
+func (s *scheduler) due(reporters []Reporter, now time.Time) []Reporter {
+	var due []Reporter
+	for _, r := range reporters {
+		last, ok := s.lastRun[r.GetName()]
+		if !ok || now.Sub(last) >= s.intervalFor(r.GetName()) {
+			due = append(due, r)
+		}
+	}
+	return due
+}
+
+func (s *scheduler) markRun(reporters []Reporter, now time.Time) {
+	for _, r := range reporters {
+		s.lastRun[r.GetName()] = now
+	}
+}
+
+// next returns how long to sleep until the next reporter is due, plus a random
+// share of the configured jitter.
+func (s *scheduler) next(now time.Time) time.Duration {
+	wait := s.interval
+	for name, last := range s.lastRun {
+		if until := last.Add(s.intervalFor(name)).Sub(now); until < wait {
+			wait = until
+		}
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	if s.jitter > 0 {
+		wait += time.Duration(rand.Int63n(int64(s.jitter)))
+	}
+	return wait
+}
+
+// runDaemon executes a run for every due reporter, then sleeps until the next
+// one is due, until ctx is cancelled. Reporters are rebuilt from config on every
+// tick so no state leaks from one run into the next. Runs are deliberately not
+// bound to ctx: a run in progress when ctx is cancelled finishes gathering and
+// sending before runDaemon returns.
+func runDaemon(ctx context.Context, c config.Config) error {
+	s := newScheduler(c)
+	for {
+		now := time.Now()
+		reporters, err := makeReporters(c)
+		if err != nil {
+			return err
+		}
+
+		if due := s.due(reporters, now); len(due) > 0 {
+			log.Info("==> STARTING SIGNAL RUNNER")
+			if err := executeReporters(context.Background(), due, c); err != nil {
+				log.Error(err)
+			}
+			s.markRun(due, now)
+		}
+
+		wait := s.next(time.Now())
+		log.Debugf("Next run in %s", wait)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			log.Info("Shutting down signal daemon")
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// executeDaemon runs the daemon until the process receives SIGINT or SIGTERM.
+func executeDaemon(c config.Config) error {
+	if c.Interval <= 0 {
+		log.Warnf("Invalid interval %s, using 1h", c.Interval)
+		c.Interval = time.Hour
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	ossignal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer ossignal.Stop(signals)
+	go func() {
+		select {
+		case sig := <-signals:
+			log.Infof("Received %s, finishing in-flight run", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	log.Infof("Starting signal daemon, interval %s, jitter %s", c.Interval, c.IntervalJitter)
+	return runDaemon(ctx, c)
+}
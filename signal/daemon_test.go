@@ -0,0 +1,88 @@
+// +build unit
+
+package signal
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-signal/config"
+)
+
+func TestSchedulerCadence(t *testing.T) {
+	c := config.DefaultConfig()
+	c.Interval = time.Hour
+	c.IntervalJitter = 0
+	c.ReporterIntervals = map[string]config.Duration{
+		"mesos": {Duration: 10 * time.Minute},
+	}
+
+	var (
+		s         = newScheduler(c)
+		now       = time.Now()
+		mesos     = &Mesos{Name: "mesos"}
+		cosmos    = &Cosmos{Name: "cosmos"}
+		reporters = []Reporter{mesos, cosmos}
+	)
+
+	if due := s.due(reporters, now); len(due) != 2 {
+		t.Fatal("Expected all reporters to be due on the first run, got", len(due))
+	}
+	s.markRun(reporters, now)
+
+	if wait := s.next(now); wait != 10*time.Minute {
+		t.Error("Expected next run in 10m, got", wait)
+	}
+
+	due := s.due(reporters, now.Add(10*time.Minute))
+	if len(due) != 1 || due[0].GetName() != "mesos" {
+		t.Error("Expected only mesos to be due after 10m, got", due)
+	}
+
+	if due := s.due(reporters, now.Add(time.Hour)); len(due) != 2 {
+		t.Error("Expected all reporters to be due after 1h, got", len(due))
+	}
+}
+
+func TestSchedulerJitter(t *testing.T) {
+	c := config.DefaultConfig()
+	c.Interval = time.Minute
+	c.IntervalJitter = time.Second
+
+	s := newScheduler(c)
+	for i := 0; i < 100; i++ {
+		if wait := s.next(time.Now()); wait < time.Minute || wait >= time.Minute+time.Second {
+			t.Fatal("Expected wait between 1m and 1m1s, got", wait)
+		}
+	}
+}
+
+func TestRunDaemonStops(t *testing.T) {
+	c := config.DefaultConfig()
+	c.FlagTest = true
+	c.Interval = time.Hour
+	c.DiagnosticsURLs = []string{fmt.Sprintf("%s/system/health/v1/report", server.URL)}
+	c.CosmosURLs = []string{fmt.Sprintf("%s/package/list", server.URL)}
+	c.MesosURLs = []string{
+		fmt.Sprintf("%s/frameworks", server.URL),
+		fmt.Sprintf("%s/metrics/snapshot", server.URL),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- runDaemon(ctx, c)
+	}()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error("Expected nil error, got", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Expected daemon to stop after cancel")
+	}
+}
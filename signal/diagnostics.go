@@ -44,100 +44,238 @@ type Diagnostics struct {
 	Method    string
 	Headers   map[string]string
 	Track     *analytics.Track
-	Error     []string
+	Error     []ReportError
 }
 
-func (d *Diagnostics) getName() string {
+func (d *Diagnostics) GetName() string {
 	return d.Name
 }
 
-func (d *Diagnostics) setReport(body []byte) error {
+func (d *Diagnostics) SetReport(body []byte) error {
 	if err := json.Unmarshal(body, &d.Report); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (d *Diagnostics) getReport() interface{} {
+func (d *Diagnostics) GetReport() interface{} {
 	return d.Report
 }
 
-func (d *Diagnostics) addHeaders(head map[string]string) {
+func (d *Diagnostics) AddHeaders(head map[string]string) {
 	for k, v := range head {
 		d.Headers[k] = v
 	}
 }
 
-func (d *Diagnostics) getHeaders() map[string]string {
+func (d *Diagnostics) GetHeaders() map[string]string {
 	return d.Headers
 }
 
-func (d *Diagnostics) getEndpoints() []string {
-	if len(d.Endpoints) != 1 {
-		log.Errorf("Diagnostics needs 1 endpoint, got %d", len(d.Endpoints))
-	}
+// EndpointCount returns the number of endpoints Diagnostics needs.
+func (d *Diagnostics) EndpointCount() int {
+	return 1
+}
+
+func (d *Diagnostics) GetEndpoints() []string {
 	return d.Endpoints
 }
 
-func (d *Diagnostics) getMethod() string {
+func (d *Diagnostics) GetMethod() string {
 	return d.Method
 }
 
-func (d *Diagnostics) getError() []string {
+func (d *Diagnostics) GetError() []ReportError {
 	return d.Error
 }
 
-func (d *Diagnostics) appendError(err string) {
+func (d *Diagnostics) AppendError(err ReportError) {
 	d.Error = append(d.Error, err)
 }
 
-func (d *Diagnostics) setTrack(c config.Config) error {
-	properties := map[string]interface{}{
-		"source":             "cluster",
-		"customerKey":        c.CustomerKey,
-		"environmentVersion": c.DCOSVersion,
-		"clusterId":          c.ClusterID,
-		"licenseId":          c.LicenseID,
-		"variant":            c.DCOSVariant,
-		"platform":           c.GenPlatform,
-		"provider":           c.GenProvider,
+// Roles of DC/OS nodes, as reported in the health keys
+const (
+	roleMaster      = "master"
+	roleAgent       = "agent"
+	rolePublicAgent = "public_agent"
+)
+
+var nodeRoles = []string{roleMaster, roleAgent, rolePublicAgent}
+
+// nodeRole returns the role of a node in the health report, which older
+// versions of diagnostics call slave and slave_public.
+func nodeRole(role string) string {
+	switch role {
+	case "agent", "slave":
+		return roleAgent
+	case "agent_public", "public_agent", "slave_public":
+		return rolePublicAgent
+	}
+	return role
+}
+
+// unhealthyOn reports whether unit is unhealthy on node: either the node
+// has error output for it, or reports it unhealthy.
+func (u *Unit) unhealthyOn(node *Node) bool {
+	errorLog, ok := node.Output[u.UnitName]
+	if !ok {
+		log.Errorf("unit %s is not in node output", u.UnitName)
+	}
+
+	if errorLog != "" {
+		log.Debugf("UNHEALTHY NODE: %s, journald log: %s", node.IP, errorLog)
+		return true
+	}
+	for _, nodeUnit := range node.Units {
+		if u.UnitName == nodeUnit.UnitName && nodeUnit.Health != 0 {
+			log.Debugf("UNHEALTHY UNIT: %s", node.Output[u.UnitName])
+			return true
+		}
+	}
+	return false
+}
+
+// UnhealthyNode is a node a unit is unhealthy on, sent when health detail is
+// enabled. Node is the node's IP, hashed unless configured otherwise, and
+// Excerpt the scrubbed tail of the unit's journal output on the node.
+type UnhealthyNode struct {
+	Node    string `json:"node,omitempty"`
+	Role    string `json:"role"`
+	Excerpt string `json:"excerpt,omitempty"`
+}
+
+// nodeID returns how node is identified in health detail. Nodes are left out
+// if their IPs are to be hashed but there is no key.
+func nodeID(hd config.HealthDetailConfig, key []byte, node *Node) string {
+	switch hd.NodeIPs {
+	case "plain":
+		return node.IP
+	case "none":
+		return ""
+	}
+	if key == nil {
+		return ""
+	}
+	return hashIP(key, node.IP)
+}
+
+// newHealthRedactor returns a redactor for health detail that knows the
+// hostnames of every node in the report.
+func (d *Diagnostics) newHealthRedactor(c config.Config) (*redactor, error) {
+	var hosts []string
+	for _, unit := range d.Report.Units {
+		for _, node := range unit.Nodes {
+			hosts = append(hosts, node.Host)
+		}
+	}
+	for _, node := range d.Report.Nodes {
+		hosts = append(hosts, node.Host)
 	}
+	return newRedactor(c.HealthDetail.Redact, hosts)
+}
+
+// TrackEvent returns the event of the Diagnostics track.
+func (d *Diagnostics) TrackEvent(c config.Config) string {
+	return c.SegmentEvent
+}
+
+func (d *Diagnostics) SetTrack(c config.Config) error {
+	properties := trackProperties(c, map[string]interface{}{})
 
 	if d.Report == nil {
 		return fmt.Errorf("%s report is nil, bailing out", d.Name)
 	}
 
+	var scrubber *redactor
+	if c.HealthDetail.Enabled {
+		r, err := d.newHealthRedactor(c)
+		if err != nil {
+			// Better to send no detail than unscrubbed detail.
+			log.Errorf("Not sending health detail: %s", err)
+			d.AppendError(ReportError{
+				Phase:   phaseTrack,
+				Class:   classIncomplete,
+				Message: fmt.Sprintf("not sending health detail: %s", err),
+			})
+		}
+		scrubber = r
+	}
+
+	var hashKey []byte
+	if scrubber != nil && c.HealthDetail.NodeIPs == "hash" {
+		key, err := nodeHashKey(c)
+		if err != nil {
+			log.Errorf("Not sending node hashes in health detail: %s", err)
+			d.AppendError(ReportError{
+				Phase:   phaseTrack,
+				Class:   classIncomplete,
+				Message: fmt.Sprintf("not sending node hashes in health detail: %s", err),
+			})
+		}
+		hashKey = key
+	}
+
+	// The health score is the share of unit instances, a unit on a node, that
+	// are healthy.
+	var instances, unhealthyInstances int
 	for _, unit := range d.Report.Units {
 		totalUnits := len(unit.Nodes)
 		totalUnhealthyUnits := 0
+		roleTotal := make(map[string]int)
+		roleUnhealthy := make(map[string]int)
+		detail := []UnhealthyNode{}
 
 		for _, node := range unit.Nodes {
-			errorLog, ok := node.Output[unit.UnitName]
-			if !ok {
-				log.Errorf("unit %s is not in node output", unit.UnitName)
-			}
-
-			if errorLog != "" {
-				log.Debugf("UNHEALTHY NODE: %s, journald log: %s", node.IP, errorLog)
+			role := nodeRole(node.Role)
+			roleTotal[role]++
+			if unit.unhealthyOn(node) {
 				totalUnhealthyUnits++
-			} else {
-				for _, nodeUnit := range node.Units {
-					if unit.UnitName == nodeUnit.UnitName {
-						if nodeUnit.Health != 0 {
-							log.Debugf("UNHEALTHY UNIT: %s", node.Output[unit.UnitName])
-							totalUnhealthyUnits++
-						}
-					}
+				roleUnhealthy[role]++
+				if scrubber != nil {
+					detail = append(detail, UnhealthyNode{
+						Node:    nodeID(c.HealthDetail, hashKey, node),
+						Role:    role,
+						Excerpt: excerpt(scrubber.scrub(node.Output[unit.UnitName]), c.HealthDetail.ExcerptBytes),
+					})
 				}
 			}
-			segmentUnitTotalKey := CreateUnitTotalKey(unit.UnitName)
-			segmentUnitUnhealthyKey := CreateUnitUnhealthyKey(unit.UnitName)
-			properties[segmentUnitTotalKey] = totalUnits
-			properties[segmentUnitUnhealthyKey] = totalUnhealthyUnits
 		}
+
+		properties[CreateUnitTotalKey(unit.UnitName)] = totalUnits
+		properties[CreateUnitUnhealthyKey(unit.UnitName)] = totalUnhealthyUnits
+		properties[CreateUnitTitleKey(unit.UnitName)] = unit.Title
+		for _, role := range nodeRoles {
+			properties[CreateUnitRoleTotalKey(unit.UnitName, role)] = roleTotal[role]
+			properties[CreateUnitRoleUnhealthyKey(unit.UnitName, role)] = roleUnhealthy[role]
+		}
+		if len(detail) > 0 {
+			properties[CreateUnitDetailKey(unit.UnitName)] = detail
+		}
+
+		instances += totalUnits
+		unhealthyInstances += totalUnhealthyUnits
+	}
+
+	nodeTotal := make(map[string]int)
+	nodeUnhealthy := make(map[string]int)
+	for _, node := range d.Report.Nodes {
+		role := nodeRole(node.Role)
+		nodeTotal[role]++
+		if node.Health != 0 {
+			nodeUnhealthy[role]++
+		}
+	}
+	for _, role := range nodeRoles {
+		properties[CreateNodeRoleTotalKey(role)] = nodeTotal[role]
+		properties[CreateNodeRoleUnhealthyKey(role)] = nodeUnhealthy[role]
 	}
+
+	if instances > 0 {
+		properties["health-score"] = float64(instances-unhealthyInstances) / float64(instances)
+	}
+
 	d.Track = &analytics.Track{
-		Event:       c.SegmentEvent,
+		Event:       d.TrackEvent(c),
 		UserId:      c.CustomerKey,
 		AnonymousId: c.ClusterID,
 		Properties:  properties,
@@ -145,14 +283,6 @@ func (d *Diagnostics) setTrack(c config.Config) error {
 	return nil
 }
 
-func (d *Diagnostics) getTrack() *analytics.Track {
+func (d *Diagnostics) GetTrack() *analytics.Track {
 	return d.Track
 }
-
-func (d *Diagnostics) sendTrack(c config.Config) error {
-	ac := CreateSegmentClient(c.SegmentKey, c.FlagVerbose)
-	defer ac.Close()
-
-	err := ac.Track(d.Track)
-	return err
-}
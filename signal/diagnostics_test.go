@@ -3,6 +3,7 @@
 package signal
 
 import (
+	"context"
 	"fmt"
 	"net/http/httptest"
 	"testing"
@@ -30,21 +31,22 @@ func TestDiagnosticsTrack(t *testing.T) {
 	c.DCOSVariant = config.DCOSVariant{"test_variant"}
 
 	for _, e := range testDiag.Endpoints {
-		pullErr := PullReport(e, &testDiag, c)
+		pullErr := PullReport(context.Background(), e, &testDiag, c)
 		if pullErr != nil {
 			t.Error("Got error pulling from test server, ", pullErr)
 		}
 	}
 
-	setupErr := testDiag.setTrack(c)
-	actualSegmentTrack := testDiag.getTrack()
+	setupErr := testDiag.SetTrack(c)
+	actualSegmentTrack := testDiag.GetTrack()
 
 	if setupErr != nil {
 		t.Error("Expected no errors running diagnostics.SetTrack(), got ", setupErr)
 	}
 
-	if len(actualSegmentTrack.Properties) != 12 {
-		t.Error("Expected 12 properties, got ", len(actualSegmentTrack.Properties))
+	// 8 common properties, 9 per unit, 6 per-role node totals and the score
+	if len(actualSegmentTrack.Properties) != 33 {
+		t.Error("Expected 33 properties, got ", len(actualSegmentTrack.Properties))
 	}
 
 	if actualSegmentTrack.Event != "health" {
@@ -122,3 +124,101 @@ func TestDiagnosticsTrack(t *testing.T) {
 		t.Error("Expected key health-unit-foo-unit-1-unhealthy to be 0, got ", val)
 	}
 }
+
+func TestDiagnosticsRoles(t *testing.T) {
+	report := &HealthReport{
+		Units: mockUnits,
+		Nodes: map[string]*Node{
+			"10.0.0.1": {Role: "master", Health: 0},
+			"10.0.0.2": {Role: "agent", Health: 1},
+			"10.0.0.3": {Role: "slave_public", Health: 0},
+		},
+	}
+	d := &Diagnostics{Name: "diagnostics", Report: report}
+	if err := d.SetTrack(config.DefaultConfig()); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	properties := d.GetTrack().Properties
+
+	// foo-unit.2 has error output on both the master and the agent.
+	for key, expected := range map[string]interface{}{
+		"health-unit-foo-unit-2-title":              "Foo Test 2",
+		"health-unit-foo-unit-2-master-total":       1,
+		"health-unit-foo-unit-2-master-unhealthy":   1,
+		"health-unit-foo-unit-2-agent-unhealthy":    1,
+		"health-unit-foo-unit-2-public_agent-total": 0,
+		"health-unit-foo-unit-1-master-unhealthy":   0,
+		"health-nodes-master-total":                 1,
+		"health-nodes-agent-unhealthy":              1,
+		"health-nodes-public_agent-total":           1,
+		"health-nodes-public_agent-unhealthy":       0,
+		"health-score":                              0.5,
+	} {
+		if properties[key] != expected {
+			t.Errorf("Expected %s to be %v, got %v", key, expected, properties[key])
+		}
+	}
+}
+
+func TestDiagnosticsHealthDetail(t *testing.T) {
+	node := &Node{
+		Role: "master",
+		IP:   "10.0.0.1",
+		Host: "master-1.example.com",
+		Output: map[string]string{
+			"foo-unit.1": "master-1.example.com: dial 10.0.0.5:5050 failed, token=s3cr3t",
+		},
+	}
+	report := &HealthReport{
+		Units: map[string]*Unit{
+			"foo-unit.1": {UnitName: "foo-unit.1", Nodes: []*Node{node}},
+		},
+	}
+	c := config.DefaultConfig()
+	c.ClusterID = "anon"
+
+	d := &Diagnostics{Name: "diagnostics", Report: report}
+	if err := d.SetTrack(c); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if detail, ok := d.GetTrack().Properties["health-unit-foo-unit-1-unhealthy-detail"]; ok {
+		t.Error("Expected no health detail unless enabled, got", detail)
+	}
+
+	c.HealthDetail.Enabled = true
+	if err := d.SetTrack(c); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	detail, ok := d.GetTrack().Properties["health-unit-foo-unit-1-unhealthy-detail"].([]UnhealthyNode)
+	if !ok || len(detail) != 1 {
+		t.Fatal("Expected detail for 1 node, got", d.GetTrack().Properties["health-unit-foo-unit-1-unhealthy-detail"])
+	}
+	key, _ := nodeHashKey(c)
+	if detail[0].Node != hashIP(key, "10.0.0.1") || detail[0].Role != roleMaster {
+		t.Error("Expected hashed master node, got", detail[0])
+	}
+	if expected := "<host>: dial <ip>:5050 failed, token=<redacted>"; detail[0].Excerpt != expected {
+		t.Errorf("Expected excerpt %q, got %q", expected, detail[0].Excerpt)
+	}
+
+	c.HealthDetail.NodeIPs = "none"
+	c.HealthDetail.Redact.Patterns = []string{"("}
+	if err := d.SetTrack(c); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if detail, ok := d.GetTrack().Properties["health-unit-foo-unit-1-unhealthy-detail"]; ok {
+		t.Error("Expected no health detail with an invalid redact pattern, got", detail)
+	}
+	if errs := d.GetError(); len(errs) != 1 || errs[0].Phase != phaseTrack {
+		t.Error("Expected track error for the invalid redact pattern, got", errs)
+	}
+
+	c.HealthDetail.Redact.Patterns = nil
+	if err := d.SetTrack(c); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	detail = d.GetTrack().Properties["health-unit-foo-unit-1-unhealthy-detail"].([]UnhealthyNode)
+	if detail[0].Node != "" {
+		t.Error("Expected no node identifier, got", detail[0].Node)
+	}
+}
@@ -0,0 +1,175 @@
+package signal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/dcos/dcos-signal/config"
+	"gopkg.in/segmentio/analytics-go.v2"
+)
+
+// Generic implements a Reporter that is defined in the signal config file
+// instead of in code. See config.GenericReporterConfig.
+type Generic struct {
+	Report    interface{}
+	Name      string
+	Event     string
+	Endpoints []string
+	Method    string
+	Headers   map[string]string
+	Body      string
+	Extract   []config.Extraction
+	Track     *analytics.Track
+	Error     []ReportError
+}
+
+// newGeneric validates gc and returns the reporter it defines.
+func newGeneric(gc config.GenericReporterConfig) (*Generic, error) {
+	if gc.Name == "" {
+		return nil, errors.New("generic reporter needs a name")
+	}
+	if gc.Event == "" {
+		return nil, fmt.Errorf("generic reporter %s needs an event", gc.Name)
+	}
+	if len(gc.Endpoints) == 0 {
+		return nil, fmt.Errorf("generic reporter %s has no endpoints", gc.Name)
+	}
+	if len(gc.Extract) == 0 {
+		return nil, fmt.Errorf("generic reporter %s has nothing to extract", gc.Name)
+	}
+	for _, e := range gc.Extract {
+		if e.Property == "" {
+			return nil, fmt.Errorf("generic reporter %s has an extraction without property", gc.Name)
+		}
+		if _, err := parsePath(e.Path); err != nil {
+			return nil, fmt.Errorf("generic reporter %s: %s", gc.Name, err)
+		}
+		switch e.Op {
+		case "", "value", "count", "sum":
+		default:
+			return nil, fmt.Errorf("generic reporter %s has unknown op %q for %s", gc.Name, e.Op, e.Property)
+		}
+	}
+
+	g := &Generic{
+		Name:      gc.Name,
+		Event:     gc.Event,
+		Endpoints: gc.Endpoints,
+		Method:    gc.Method,
+		Headers: map[string]string{
+			"content-type": "application/json",
+		},
+		Body:    gc.Body,
+		Extract: gc.Extract,
+	}
+	if g.Method == "" {
+		g.Method = "GET"
+	}
+	if g.Body == "" {
+		g.Body = "{}"
+	}
+	for k, v := range gc.Headers {
+		g.Headers[k] = v
+	}
+	return g, nil
+}
+
+func (g *Generic) GetName() string {
+	return g.Name
+}
+
+// SetReport merges the response into the report. Like Mesos, a report from
+// several endpoints is the union of their top level keys; any response that is
+// not a JSON object replaces the report.
+func (g *Generic) SetReport(body []byte) error {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return err
+	}
+
+	existing, isObject := g.Report.(map[string]interface{})
+	incoming, incomingIsObject := doc.(map[string]interface{})
+	if isObject && incomingIsObject {
+		for k, v := range incoming {
+			existing[k] = v
+		}
+		return nil
+	}
+	g.Report = doc
+	return nil
+}
+
+func (g *Generic) GetReport() interface{} {
+	return g.Report
+}
+
+func (g *Generic) AddHeaders(head map[string]string) {
+	for k, v := range head {
+		g.Headers[k] = v
+	}
+}
+
+func (g *Generic) GetHeaders() map[string]string {
+	return g.Headers
+}
+
+func (g *Generic) GetEndpoints() []string {
+	return g.Endpoints
+}
+
+func (g *Generic) GetMethod() string {
+	return g.Method
+}
+
+func (g *Generic) GetBody() string {
+	return g.Body
+}
+
+func (g *Generic) GetError() []ReportError {
+	return g.Error
+}
+
+func (g *Generic) AppendError(err ReportError) {
+	g.Error = append(g.Error, err)
+}
+
+// TrackEvent returns the event of the Generic track.
+func (g *Generic) TrackEvent(config.Config) string {
+	return g.Event
+}
+
+// SetTrack extracts every configured property from the report. A property
+// that cannot be extracted is left out and recorded as an error, so one bad
+// path does not cost the other properties.
+func (g *Generic) SetTrack(c config.Config) error {
+	if g.Report == nil {
+		return fmt.Errorf("%s report is nil, bailing out", g.Name)
+	}
+
+	properties := trackProperties(c, map[string]interface{}{})
+	for _, e := range g.Extract {
+		value, err := extract(g.Report, e.Path, e.Op)
+		if err != nil {
+			g.AppendError(ReportError{
+				Phase:   phaseTrack,
+				Class:   classIncomplete,
+				Message: fmt.Sprintf("%s: %s", e.Property, err),
+			})
+			continue
+		}
+		properties[e.Property] = value
+	}
+
+	g.Track = &analytics.Track{
+		Event:       g.TrackEvent(c),
+		UserId:      c.CustomerKey,
+		AnonymousId: c.ClusterID,
+		Properties:  properties,
+	}
+	return nil
+}
+
+func (g *Generic) GetTrack() *analytics.Track {
+	return g.Track
+}
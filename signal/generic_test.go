@@ -0,0 +1,149 @@
+// +build unit
+
+package signal
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dcos/dcos-signal/config"
+)
+
+func TestGenericReporter(t *testing.T) {
+	var body string
+	info := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		fmt.Fprint(w, `{"version": "1.9.0", "apps": [{"instances": 2}, {"instances": 3}]}`)
+	}))
+	defer info.Close()
+
+	c := config.DefaultConfig()
+	c.ClusterID = "anon"
+	c.ExtraHeaders = map[string]string{"Authorization": "token=foo"}
+	c.GenericReporters = []config.GenericReporterConfig{{
+		Name:  "marathon-info",
+		Event: "marathon_track",
+		Body:  `{"embed": "apps"}`,
+		Endpoints: []string{
+			info.URL,
+			fmt.Sprintf("%s/metrics/snapshot", server.URL),
+		},
+		Extract: []config.Extraction{
+			{Property: "version", Path: "$.version"},
+			{Property: "instances", Path: "$.apps[*].instances", Op: "sum"},
+			{Property: "tasks", Path: "$['master/tasks_running']"},
+			{Property: "missing", Path: "$.nope"},
+		},
+	}}
+	c.Reporters = map[string]bool{"diagnostics": false, "cosmos": false, "mesos": false}
+
+	reporters, err := makeReporters(c)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if len(reporters) != 1 || reporters[0].GetName() != "marathon-info" {
+		t.Fatal("Expected only the generic reporter, got", reporters)
+	}
+	g := reporters[0]
+
+	if g.GetHeaders()["Authorization"] != "token=foo" {
+		t.Error("Expected extra headers on generic reporter, got", g.GetHeaders())
+	}
+
+	if err := runner(context.Background(), reporters, c); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	if body != `{"embed": "apps"}` {
+		t.Error("Expected configured request body, got", body)
+	}
+
+	track := g.GetTrack()
+	if track == nil {
+		t.Fatal("Expected track, got nil")
+	}
+	if track.Event != "marathon_track" {
+		t.Error("Expected event marathon_track, got", track.Event)
+	}
+	if track.Properties["version"] != "1.9.0" || track.Properties["instances"] != float64(5) {
+		t.Error("Expected version and instances from the first endpoint, got", track.Properties)
+	}
+	if track.Properties["tasks"] != float64(4) {
+		t.Error("Expected tasks from the second endpoint, got", track.Properties["tasks"])
+	}
+	if _, ok := track.Properties["missing"]; ok {
+		t.Error("Expected missing property to be left out")
+	}
+	if track.Properties["clusterId"] != "anon" {
+		t.Error("Expected common properties on generic track, got", track.Properties)
+	}
+
+	if errs := g.GetError(); len(errs) != 1 || errs[0].Phase != phaseTrack {
+		t.Error("Expected 1 error for the missing path, got", errs)
+	}
+}
+
+func TestNewGenericValidation(t *testing.T) {
+	valid := config.GenericReporterConfig{
+		Name:      "foo",
+		Event:     "foo_track",
+		Endpoints: []string{"http://localhost"},
+		Extract:   []config.Extraction{{Property: "bar", Path: "$.bar"}},
+	}
+
+	g, err := newGeneric(valid)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if g.GetMethod() != "GET" || g.GetBody() != "{}" {
+		t.Error("Expected GET with empty JSON body by default, got", g.GetMethod(), g.GetBody())
+	}
+
+	noName := valid
+	noName.Name = ""
+	noEvent := valid
+	noEvent.Event = ""
+	noEndpoints := valid
+	noEndpoints.Endpoints = nil
+	noExtract := valid
+	noExtract.Extract = nil
+	badPath := valid
+	badPath.Extract = []config.Extraction{{Property: "bar", Path: "$.bar["}}
+	badOp := valid
+	badOp.Extract = []config.Extraction{{Property: "bar", Path: "$.bar", Op: "average"}}
+
+	for _, gc := range []config.GenericReporterConfig{noName, noEvent, noEndpoints, noExtract, badPath, badOp} {
+		if _, err := newGeneric(gc); err == nil {
+			t.Errorf("Expected error for %+v, got nil", gc)
+		}
+	}
+
+	c := config.DefaultConfig()
+	c.GenericReporters = []config.GenericReporterConfig{valid, valid}
+	if _, err := makeReporters(c); err == nil {
+		t.Error("Expected error for duplicate generic reporter names, got nil")
+	}
+
+	valid.Name = "mesos"
+	c.GenericReporters = []config.GenericReporterConfig{valid}
+	if _, err := makeReporters(c); err == nil {
+		t.Error("Expected error for generic reporter named like a registered one, got nil")
+	}
+
+	// Configs from before the marathon reporter may use its name, which is
+	// fine for as long as it does not run.
+	valid.Name = "marathon"
+	c.GenericReporters = []config.GenericReporterConfig{valid}
+	if _, err := makeReporters(c); err != nil {
+		t.Error("Expected nil error for generic marathon reporter without marathon_urls, got", err)
+	}
+	c.MarathonURLs = []string{"http://localhost/1", "http://localhost/2", "http://localhost/3", "http://localhost/4"}
+	if _, err := makeReporters(c); err == nil {
+		t.Error("Expected error for generic reporter named like the running marathon reporter, got nil")
+	}
+}
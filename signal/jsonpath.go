@@ -0,0 +1,169 @@
+package signal
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pathStep is one step of a parsed JSON path: an object key, an array index or
+// a wildcard over all array elements or object values.
+type pathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parsePath parses a JSONPath-style path. It supports dotted keys, bracketed
+// indices and quoted keys, and "*" or "[*]" wildcards, e.g.
+// "$.apps[*].container.type" or "$['master/tasks_running']". The leading "$"
+// is optional.
+func parsePath(path string) ([]pathStep, error) {
+	p := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	var steps []pathStep
+	for len(p) > 0 {
+		switch p[0] {
+		case '.':
+			p = p[1:]
+			end := strings.IndexAny(p, ".[")
+			if end == -1 {
+				end = len(p)
+			}
+			key := p[:end]
+			if key == "" {
+				return nil, fmt.Errorf("empty key in path %q", path)
+			}
+			if key == "*" {
+				steps = append(steps, pathStep{wildcard: true})
+			} else {
+				steps = append(steps, pathStep{key: key})
+			}
+			p = p[end:]
+		case '[':
+			end := strings.IndexByte(p, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated [ in path %q", path)
+			}
+			inner := p[1:end]
+			switch {
+			case inner == "*":
+				steps = append(steps, pathStep{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				steps = append(steps, pathStep{key: inner[1 : len(inner)-1]})
+			default:
+				i, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %q in path %q", inner, path)
+				}
+				steps = append(steps, pathStep{index: i, isIndex: true})
+			}
+			p = p[end+1:]
+		default:
+			// A path may start with a bare key, as in "apps[0].id".
+			p = "." + p
+		}
+	}
+	return steps, nil
+}
+
+// evalPath evaluates steps against a decoded JSON document. It returns all
+// matching values, and whether the path contains a wildcard and so describes a
+// list of values rather than a single one.
+func evalPath(doc interface{}, steps []pathStep) ([]interface{}, bool) {
+	var (
+		current  = []interface{}{doc}
+		wildcard = false
+	)
+	for _, step := range steps {
+		var next []interface{}
+		for _, v := range current {
+			switch node := v.(type) {
+			case map[string]interface{}:
+				if step.wildcard {
+					keys := make([]string, 0, len(node))
+					for k := range node {
+						keys = append(keys, k)
+					}
+					sort.Strings(keys)
+					for _, k := range keys {
+						next = append(next, node[k])
+					}
+				} else if child, ok := node[step.key]; ok && !step.isIndex {
+					next = append(next, child)
+				}
+			case []interface{}:
+				if step.wildcard {
+					next = append(next, node...)
+				} else if step.isIndex {
+					i := step.index
+					if i < 0 {
+						i += len(node)
+					}
+					if i >= 0 && i < len(node) {
+						next = append(next, node[i])
+					}
+				}
+			}
+		}
+		if step.wildcard {
+			wildcard = true
+		}
+		current = next
+	}
+	return current, wildcard
+}
+
+// extract evaluates path against doc and applies op to the result.
+func extract(doc interface{}, path, op string) (interface{}, error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	values, wildcard := evalPath(doc, steps)
+
+	switch op {
+	case "", "value":
+		if wildcard {
+			if values == nil {
+				values = []interface{}{}
+			}
+			return values, nil
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("path %q not found", path)
+		}
+		return values[0], nil
+	case "count":
+		if wildcard {
+			return len(values), nil
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("path %q not found", path)
+		}
+		switch v := values[0].(type) {
+		case []interface{}:
+			return len(v), nil
+		case map[string]interface{}:
+			return len(v), nil
+		}
+		return nil, fmt.Errorf("path %q is neither a list nor an object, cannot count it", path)
+	case "sum":
+		if !wildcard && len(values) == 1 {
+			if list, ok := values[0].([]interface{}); ok {
+				values = list
+			}
+		}
+		var sum float64
+		for _, v := range values {
+			n, ok := v.(float64)
+			if !ok {
+				return nil, fmt.Errorf("path %q has non-numeric value %v, cannot sum it", path, v)
+			}
+			sum += n
+		}
+		return sum, nil
+	}
+	return nil, fmt.Errorf("unknown op %q", op)
+}
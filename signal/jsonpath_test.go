@@ -0,0 +1,71 @@
+// +build unit
+
+package signal
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const testJSONDoc = `
+{
+  "apps": [
+    {"id": "/kafka", "instances": 3, "container": {"type": "DOCKER"}},
+    {"id": "/spark", "instances": 2, "container": {"type": "MESOS"}}
+  ],
+  "master/tasks_running": 5,
+  "info": {"version": "1.9.0", "leader": "10.0.0.1"}
+}`
+
+func TestExtract(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(testJSONDoc), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		path     string
+		op       string
+		expected interface{}
+	}{
+		{"$.info.version", "", "1.9.0"},
+		{"info.version", "value", "1.9.0"},
+		{"$['master/tasks_running']", "", float64(5)},
+		{"$.apps[0].id", "", "/kafka"},
+		{"$.apps[-1].id", "", "/spark"},
+		{"$.apps[*].container.type", "", []interface{}{"DOCKER", "MESOS"}},
+		{"$.info.*", "", []interface{}{"10.0.0.1", "1.9.0"}},
+		{"$.apps", "count", 2},
+		{"$.info", "count", 2},
+		{"$.apps[*].id", "count", 2},
+		{"$.apps[*].instances", "sum", float64(5)},
+		{"$.missing[*]", "", []interface{}{}},
+	} {
+		actual, err := extract(doc, tc.path, tc.op)
+		if err != nil {
+			t.Errorf("Expected nil error for %s, got %s", tc.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("Expected %v for %s %s, got %v", tc.expected, tc.op, tc.path, actual)
+		}
+	}
+
+	for _, tc := range []struct {
+		path string
+		op   string
+	}{
+		{"$.missing", ""},
+		{"$.info.version", "count"},
+		{"$.apps[*].id", "sum"},
+		{"$.apps", "median"},
+		{"$.apps[", ""},
+		{"$.apps[x]", ""},
+		{"$..apps", ""},
+	} {
+		if _, err := extract(doc, tc.path, tc.op); err == nil {
+			t.Errorf("Expected error for %s %s, got nil", tc.op, tc.path)
+		}
+	}
+}
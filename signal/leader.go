@@ -0,0 +1,101 @@
+package signal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/dcos/dcos-signal/config"
+)
+
+// localIPs returns the addresses of this host's network interfaces.
+var localIPs = func() ([]net.IP, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+	var ips []net.IP
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			ips = append(ips, ipNet.IP)
+		}
+	}
+	return ips, nil
+}
+
+// leadingMaster returns the host of the elected Mesos master, which
+// /master/redirect on the master that c.MesosURLs point to redirects to.
+func leadingMaster(ctx context.Context, c config.Config) (string, error) {
+	redirectURL, err := mesosMasterURL(c, "/master/redirect")
+	if err != nil {
+		return "", err
+	}
+
+	client := newHTTPClient(redirectURL, c)
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	resp, err := doRequest(client, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", redirectURL.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range c.ExtraHeaders {
+			req.Header.Set(k, v)
+		}
+		return req, nil
+	}, c)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusTemporaryRedirect:
+	case http.StatusServiceUnavailable:
+		return "", errors.New("no leading Mesos master elected")
+	default:
+		return "", fmt.Errorf("response %s %s: %s", resp.Proto, redirectURL.String(), resp.Status)
+	}
+
+	// The location has no scheme, e.g. //10.0.0.1:5050
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return "", err
+	}
+	if location.Hostname() == "" {
+		return "", fmt.Errorf("no leading Mesos master in redirect to %q", resp.Header.Get("Location"))
+	}
+	return location.Hostname(), nil
+}
+
+// isLeadingMaster reports whether the elected Mesos master runs on this host.
+// Signal runs on every master, but mesos_urls usually address leader.mesos,
+// which always answers as the leader, so the leader's address is compared
+// against the addresses of this host rather than against the master asked.
+func isLeadingMaster(ctx context.Context, c config.Config) (bool, error) {
+	leader, err := leadingMaster(ctx, c)
+	if err != nil {
+		return false, err
+	}
+	leaderAddrs, err := net.DefaultResolver.LookupIPAddr(ctx, leader)
+	if err != nil {
+		return false, err
+	}
+	ips, err := localIPs()
+	if err != nil {
+		return false, err
+	}
+
+	for _, addr := range leaderAddrs {
+		for _, ip := range ips {
+			if addr.IP.Equal(ip) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
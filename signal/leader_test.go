@@ -0,0 +1,111 @@
+// +build unit
+
+package signal
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dcos/dcos-signal/config"
+)
+
+// masterServer answers /master/redirect like a Mesos master, redirecting to
+// leader or, if it is empty, telling that no leader is elected.
+func masterServer(leader string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/master/redirect" {
+			http.NotFound(w, r)
+			return
+		}
+		if leader == "" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Location", "//"+leader+":5050")
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	}))
+}
+
+// setLocalIPs makes ips the addresses of this host, until the returned func
+// restores them.
+func setLocalIPs(ips ...string) func() {
+	orig := localIPs
+	localIPs = func() ([]net.IP, error) {
+		var parsed []net.IP
+		for _, ip := range ips {
+			parsed = append(parsed, net.ParseIP(ip))
+		}
+		return parsed, nil
+	}
+	return func() { localIPs = orig }
+}
+
+func TestIsLeadingMaster(t *testing.T) {
+	// leader.mesos always resolves to the leader, whichever master asks.
+	leaderMesos := masterServer("10.0.0.1")
+	defer leaderMesos.Close()
+	electing := masterServer("")
+	defer electing.Close()
+
+	c := config.DefaultConfig()
+	c.MesosURLs = []string{fmt.Sprintf("%s/frameworks", leaderMesos.URL)}
+
+	defer setLocalIPs("127.0.0.1", "10.0.0.1")()
+	if ok, err := isLeadingMaster(context.Background(), c); !ok || err != nil {
+		t.Error("Expected leader, got", ok, err)
+	}
+
+	defer setLocalIPs("127.0.0.1", "10.0.0.2")()
+	if ok, err := isLeadingMaster(context.Background(), c); ok || err != nil {
+		t.Error("Expected follower asking the leader, got", ok, err)
+	}
+
+	c.MesosURLs = []string{fmt.Sprintf("%s/frameworks", electing.URL)}
+	if _, err := isLeadingMaster(context.Background(), c); err == nil {
+		t.Error("Expected error without an elected leader, got nil")
+	}
+
+	c.MesosURLs = nil
+	if _, err := isLeadingMaster(context.Background(), c); err == nil {
+		t.Error("Expected error without mesos_urls, got nil")
+	}
+}
+
+func TestExecuteReportersFollowerSkips(t *testing.T) {
+	follower := masterServer("10.0.0.1")
+	defer follower.Close()
+	defer setLocalIPs("127.0.0.1", "10.0.0.2")()
+
+	sent := 0
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent++
+	}))
+	defer hook.Close()
+
+	c := config.DefaultConfig()
+	c.ClusterID = "anon"
+	c.MesosURLs = []string{fmt.Sprintf("%s/frameworks", follower.URL)}
+	c.Sinks = []config.SinkConfig{{Type: "webhook", URL: hook.URL}}
+	newCosmos := func() []Reporter {
+		return []Reporter{&Cosmos{
+			Name:      "cosmos",
+			Endpoints: []string{fmt.Sprintf("%s/package/list", server.URL)},
+			Method:    "POST",
+		}}
+	}
+
+	executeReporters(context.Background(), newCosmos(), c)
+	if sent != 0 {
+		t.Error("Expected follower not to send, sent", sent)
+	}
+
+	c.LeaderOnly = false
+	executeReporters(context.Background(), newCosmos(), c)
+	if sent != 1 {
+		t.Error("Expected follower to send with -leader-only=false, sent", sent)
+	}
+}
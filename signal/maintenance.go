@@ -0,0 +1,309 @@
+package signal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dcos/dcos-signal/config"
+	"gopkg.in/segmentio/analytics-go.v2"
+)
+
+// MaintenanceReport is composed of the responses of the Mesos master
+// /master/maintenance/schedule, /master/maintenance/status and
+// /metrics/snapshot endpoints, and optionally /registrar(1)/registry.
+type MaintenanceReport struct {
+	Windows      []MaintenanceWindow `json:"windows"`
+	Draining     []MachineID         `json:"draining"`
+	Down         []MachineID         `json:"down"`
+	AgentMetrics *AgentMetrics       `json:"agent_metrics"`
+	Unreachable  []RegistryAgent     `json:"unreachable"`
+	Gone         []RegistryAgent     `json:"gone"`
+}
+
+// AgentMetrics are the agent gauges and counters of the Mesos master
+// /metrics/snapshot response.
+type AgentMetrics struct {
+	Unreachable  float64 `json:"master/slaves_unreachable"`
+	Disconnected float64 `json:"master/slaves_disconnected"`
+	Removals     float64 `json:"master/slave_removals"`
+}
+
+// MachineID identifies a machine in the maintenance endpoints
+type MachineID struct {
+	Hostname string `json:"hostname"`
+	IP       string `json:"ip"`
+}
+
+// MaintenanceWindow defines a window of the /maintenance/schedule response
+type MaintenanceWindow struct {
+	MachineIDs     []MachineID `json:"machine_ids"`
+	Unavailability struct {
+		Start    mesosTimeInfo  `json:"start"`
+		Duration *mesosDuration `json:"duration"`
+	} `json:"unavailability"`
+}
+
+// RegistryAgent defines an unreachable or gone agent in the registry, with the
+// time it was marked as such.
+type RegistryAgent struct {
+	ID struct {
+		Value string `json:"value"`
+	} `json:"id"`
+	Timestamp mesosTimeInfo `json:"timestamp"`
+}
+
+// mesosTimeInfo and mesosDuration are the TimeInfo and DurationInfo protobufs
+type mesosTimeInfo struct {
+	Nanoseconds int64 `json:"nanoseconds"`
+}
+
+func (t mesosTimeInfo) time() time.Time {
+	return time.Unix(0, t.Nanoseconds)
+}
+
+type mesosDuration struct {
+	Nanoseconds int64 `json:"nanoseconds"`
+}
+
+// end returns when the window ends, or false if it is open ended.
+func (w MaintenanceWindow) end() (time.Time, bool) {
+	if w.Unavailability.Duration == nil {
+		return time.Time{}, false
+	}
+	return w.Unavailability.Start.time().Add(time.Duration(w.Unavailability.Duration.Nanoseconds)), true
+}
+
+// durationStats returns the longest and the average of durations, in seconds.
+func durationStats(durations []time.Duration) (float64, float64) {
+	var max, total time.Duration
+	for _, d := range durations {
+		total += d
+		if d > max {
+			max = d
+		}
+	}
+	return max.Seconds(), (total / time.Duration(len(durations))).Seconds()
+}
+
+// Maintenance implements a Reporter for the maintenance schedule and status of
+// the Mesos master, and the agents it lost track of
+type Maintenance struct {
+	Report    *MaintenanceReport
+	Name      string
+	Endpoints []string
+	// Registry is set if the registry is read for gone agents and how long
+	// agents have been unreachable
+	Registry bool
+	Method   string
+	Headers  map[string]string
+	Track    *analytics.Track
+	Error    []ReportError
+
+	now func() time.Time
+}
+
+func (m *Maintenance) GetName() string {
+	return m.Name
+}
+
+// SetReport is not used, since the endpoint of a response decides how it is
+// read. See SetEndpointReport.
+func (m *Maintenance) SetReport(body []byte) error {
+	return fmt.Errorf("%s needs to know the endpoint of a report", m.Name)
+}
+
+func (m *Maintenance) SetEndpointReport(endpoint string, body []byte) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	if m.Report == nil {
+		m.Report = &MaintenanceReport{}
+	}
+
+	switch path := strings.TrimSuffix(u.Path, "/"); {
+	case strings.HasSuffix(path, "/maintenance/schedule"):
+		var schedule struct {
+			Windows []MaintenanceWindow `json:"windows"`
+		}
+		if err := json.Unmarshal(body, &schedule); err != nil {
+			return err
+		}
+		m.Report.Windows = schedule.Windows
+	case strings.HasSuffix(path, "/maintenance/status"):
+		var status struct {
+			Draining []struct {
+				ID MachineID `json:"id"`
+			} `json:"draining_machines"`
+			Down []MachineID `json:"down_machines"`
+		}
+		if err := json.Unmarshal(body, &status); err != nil {
+			return err
+		}
+		m.Report.Draining = nil
+		for _, d := range status.Draining {
+			m.Report.Draining = append(m.Report.Draining, d.ID)
+		}
+		m.Report.Down = status.Down
+	case strings.HasSuffix(path, "/metrics/snapshot"):
+		var metrics AgentMetrics
+		if err := json.Unmarshal(body, &metrics); err != nil {
+			return err
+		}
+		m.Report.AgentMetrics = &metrics
+	case strings.HasSuffix(path, "/registry"):
+		var registry struct {
+			Unreachable struct {
+				Agents []RegistryAgent `json:"slaves"`
+			} `json:"unreachable"`
+			Gone struct {
+				Agents []RegistryAgent `json:"slaves"`
+			} `json:"gone"`
+		}
+		if err := json.Unmarshal(body, &registry); err != nil {
+			return err
+		}
+		m.Report.Unreachable = registry.Unreachable.Agents
+		m.Report.Gone = registry.Gone.Agents
+	default:
+		return fmt.Errorf("unknown maintenance endpoint %s", endpoint)
+	}
+	return nil
+}
+
+func (m *Maintenance) GetReport() interface{} {
+	return m.Report
+}
+
+func (m *Maintenance) AddHeaders(head map[string]string) {
+	for k, v := range head {
+		m.Headers[k] = v
+	}
+}
+
+func (m *Maintenance) GetHeaders() map[string]string {
+	return m.Headers
+}
+
+// EndpointCount returns the number of endpoints Maintenance needs.
+func (m *Maintenance) EndpointCount() int {
+	if m.Registry {
+		return 4
+	}
+	return 3
+}
+
+func (m *Maintenance) GetEndpoints() []string {
+	return m.Endpoints
+}
+
+func (m *Maintenance) GetMethod() string {
+	return m.Method
+}
+
+func (m *Maintenance) GetError() []ReportError {
+	return m.Error
+}
+
+func (m *Maintenance) AppendError(err ReportError) {
+	m.Error = append(m.Error, err)
+}
+
+// TrackEvent returns the event of the Maintenance track.
+func (m *Maintenance) TrackEvent(config.Config) string {
+	return "maintenance_track"
+}
+
+func (m *Maintenance) SetTrack(c config.Config) error {
+	if m.Report == nil {
+		return fmt.Errorf("%s report is nil, bailing out", m.Name)
+	}
+	now := time.Now()
+	if m.now != nil {
+		now = m.now()
+	}
+
+	windows := make(map[MachineID]MaintenanceWindow)
+	scheduled := 0
+	for _, w := range m.Report.Windows {
+		for _, id := range w.MachineIDs {
+			windows[id] = w
+			if w.Unavailability.Start.time().After(now) {
+				scheduled++
+			}
+		}
+	}
+
+	// Draining and down machines are in their window since it started, and
+	// overdue once it ended.
+	overdue := 0
+	inWindow := func(machines []MachineID) []time.Duration {
+		var durations []time.Duration
+		for _, id := range machines {
+			w, ok := windows[id]
+			if !ok {
+				continue
+			}
+			if since := now.Sub(w.Unavailability.Start.time()); since > 0 {
+				durations = append(durations, since)
+			}
+			if end, ok := w.end(); ok && now.After(end) {
+				overdue++
+			}
+		}
+		return durations
+	}
+	draining := inWindow(m.Report.Draining)
+	down := inWindow(m.Report.Down)
+
+	var unreachable []time.Duration
+	for _, a := range m.Report.Unreachable {
+		unreachable = append(unreachable, now.Sub(a.Timestamp.time()))
+	}
+
+	// Durations are left out when there is nothing to measure, and agent
+	// counts when the metrics or the registry they come from are not read, so
+	// they cannot be mistaken for a real 0.
+	properties := trackProperties(c, map[string]interface{}{
+		"maintenance_windows": len(m.Report.Windows),
+		"machines_scheduled":  scheduled,
+		"machines_draining":   len(m.Report.Draining),
+		"machines_down":       len(m.Report.Down),
+		"machines_overdue":    overdue,
+	})
+	if metrics := m.Report.AgentMetrics; metrics != nil {
+		properties["agents_unreachable"] = metrics.Unreachable
+		properties["agents_disconnected"] = metrics.Disconnected
+		properties["agent_removals"] = metrics.Removals
+	}
+	if m.Registry {
+		properties["agents_gone"] = len(m.Report.Gone)
+	}
+	for prefix, durations := range map[string][]time.Duration{
+		"draining":    draining,
+		"down":        down,
+		"unreachable": unreachable,
+	} {
+		if len(durations) == 0 {
+			continue
+		}
+		max, avg := durationStats(durations)
+		properties[prefix+"_seconds_max"] = max
+		properties[prefix+"_seconds_avg"] = avg
+	}
+
+	m.Track = &analytics.Track{
+		Event:       m.TrackEvent(c),
+		UserId:      c.CustomerKey,
+		AnonymousId: c.ClusterID,
+		Properties:  properties,
+	}
+	return nil
+}
+
+func (m *Maintenance) GetTrack() *analytics.Track {
+	return m.Track
+}
@@ -0,0 +1,139 @@
+// +build unit
+
+package signal
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-signal/config"
+)
+
+// The mocks below are relative to 2019-01-14T12:00:00Z.
+const (
+	mockMaintenanceNow = 1547467200
+
+	// h1 and h2 are in a window that started 2h ago and lasted 1h, h3 is in a
+	// window starting in an hour.
+	mockMaintenanceSchedule = `
+{
+  "windows": [
+    {"machine_ids": [{"hostname": "h1", "ip": "10.0.0.1"}, {"hostname": "h2", "ip": "10.0.0.2"}],
+     "unavailability": {"start": {"nanoseconds": 1547460000000000000}, "duration": {"nanoseconds": 3600000000000}}},
+    {"machine_ids": [{"hostname": "h3", "ip": "10.0.0.3"}],
+     "unavailability": {"start": {"nanoseconds": 1547470800000000000}}}
+  ]
+}`
+	mockMaintenanceStatus = `
+{
+  "draining_machines": [
+    {"id": {"hostname": "h1", "ip": "10.0.0.1"},
+     "statuses": [{"slave_id": {"value": "a1"}, "framework_id": {"value": "fw-1"}, "status": "ACCEPT"}]}
+  ],
+  "down_machines": [{"hostname": "h2", "ip": "10.0.0.2"}]
+}`
+	// u1 was marked unreachable 30m ago, u2 90m ago.
+	mockRegistry = `
+{
+  "master": {"info": {"id": "m1"}},
+  "slaves": {"slaves": []},
+  "unreachable": {"slaves": [
+    {"id": {"value": "u1"}, "timestamp": {"nanoseconds": 1547465400000000000}},
+    {"id": {"value": "u2"}, "timestamp": {"nanoseconds": 1547461800000000000}}
+  ]},
+  "gone": {"slaves": [{"id": {"value": "g1"}, "timestamp": {"nanoseconds": 1547400000000000000}}]}
+}`
+)
+
+func testMaintenance(t *testing.T, c config.Config) *Maintenance {
+	r := findReporter(t, c, "maintenance")
+	if r == nil {
+		t.Fatal("Expected maintenance reporter when mesos_urls are set")
+	}
+	return r.(*Maintenance)
+}
+
+func TestMaintenanceTrack(t *testing.T) {
+	c := config.DefaultConfig()
+	c.MesosURLs = []string{fmt.Sprintf("%s/frameworks", server.URL), fmt.Sprintf("%s/metrics/snapshot", server.URL)}
+	c.MaintenanceRegistry = true
+
+	m := testMaintenance(t, c)
+	m.now = func() time.Time { return time.Unix(mockMaintenanceNow, 0) }
+
+	if err := runner(context.Background(), []Reporter{m}, c); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if errs := m.GetError(); len(errs) != 0 {
+		t.Fatal("Expected no errors, got", errs)
+	}
+
+	track := m.GetTrack()
+	if track.Event != "maintenance_track" {
+		t.Error("Expected event maintenance_track, got", track.Event)
+	}
+	for key, expected := range map[string]interface{}{
+		"maintenance_windows":     2,
+		"machines_scheduled":      1,
+		"machines_draining":       1,
+		"machines_down":           1,
+		"machines_overdue":        2,
+		"agents_unreachable":      2.0,
+		"agents_gone":             1,
+		"draining_seconds_max":    7200.0,
+		"down_seconds_avg":        7200.0,
+		"unreachable_seconds_max": 5400.0,
+		"unreachable_seconds_avg": 3600.0,
+	} {
+		if track.Properties[key] != expected {
+			t.Errorf("Expected %s to be %v, got %v", key, expected, track.Properties[key])
+		}
+	}
+}
+
+func TestMaintenanceWithoutRegistry(t *testing.T) {
+	c := config.DefaultConfig()
+	c.MesosURLs = []string{fmt.Sprintf("%s/frameworks", server.URL), fmt.Sprintf("%s/metrics/snapshot", server.URL)}
+
+	m := testMaintenance(t, c)
+	if len(m.GetEndpoints()) != 3 {
+		t.Fatal("Expected only the maintenance and metrics endpoints without maintenance_registry, got", m.GetEndpoints())
+	}
+	if err := runner(context.Background(), []Reporter{m}, c); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if errs := m.GetError(); len(errs) != 0 {
+		t.Fatal("Expected no errors, got", errs)
+	}
+
+	track := m.GetTrack()
+	for key, expected := range map[string]interface{}{
+		"machines_down":       1,
+		"agents_unreachable":  2.0,
+		"agents_disconnected": 1.0,
+		"agent_removals":      1.0,
+	} {
+		if track.Properties[key] != expected {
+			t.Errorf("Expected %s to be %v, got %v", key, expected, track.Properties[key])
+		}
+	}
+	for _, key := range []string{"agents_gone", "unreachable_seconds_max"} {
+		if _, ok := track.Properties[key]; ok {
+			t.Errorf("Expected no %s without maintenance_registry", key)
+		}
+	}
+}
+
+func TestMaintenanceNothingToMeasure(t *testing.T) {
+	m := &Maintenance{Name: "maintenance", Report: &MaintenanceReport{}}
+	if err := m.SetTrack(config.DefaultConfig()); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	for _, key := range []string{"draining_seconds_max", "down_seconds_max", "unreachable_seconds_max"} {
+		if _, ok := m.GetTrack().Properties[key]; ok {
+			t.Errorf("Expected no %s without machines or agents", key)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package signal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/dcos/dcos-signal/config"
+	"gopkg.in/segmentio/analytics-go.v2"
+)
+
+// MarathonReport is composed of the responses of the Marathon /v2/apps,
+// /v2/pods, /v2/deployments and /v2/info endpoints.
+type MarathonReport struct {
+	Apps        []MarathonApp        `json:"apps"`
+	Pods        []MarathonPod        `json:"pods"`
+	Deployments []MarathonDeployment `json:"deployments"`
+	Info        MarathonInfo         `json:"info"`
+}
+
+// MarathonApp defines the fields of an app in the /v2/apps response
+type MarathonApp struct {
+	ID        string `json:"id"`
+	Instances int    `json:"instances"`
+	Container *struct {
+		Type string `json:"type"`
+	} `json:"container"`
+	HealthChecks   []json.RawMessage `json:"healthChecks"`
+	TasksHealthy   int               `json:"tasksHealthy"`
+	TasksUnhealthy int               `json:"tasksUnhealthy"`
+}
+
+// MarathonPod defines the fields of a pod in the /v2/pods response
+type MarathonPod struct {
+	ID      string `json:"id"`
+	Scaling *struct {
+		Instances *int `json:"instances"`
+	} `json:"scaling"`
+}
+
+// MarathonDeployment defines the fields of a deployment in the
+// /v2/deployments response
+type MarathonDeployment struct {
+	ID           string   `json:"id"`
+	AffectedApps []string `json:"affectedApps"`
+	AffectedPods []string `json:"affectedPods"`
+}
+
+// MarathonInfo defines the fields of the /v2/info response
+type MarathonInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Health states an app is counted under in apps_by_health.
+const (
+	marathonSuspended      = "suspended"
+	marathonNoHealthChecks = "no_health_checks"
+	marathonUnhealthy      = "unhealthy"
+	marathonHealthy        = "healthy"
+	marathonStarting       = "starting"
+)
+
+// healthState returns the health state of the app for apps_by_health.
+func (a MarathonApp) healthState() string {
+	switch {
+	case a.Instances == 0:
+		return marathonSuspended
+	case len(a.HealthChecks) == 0:
+		return marathonNoHealthChecks
+	case a.TasksUnhealthy > 0:
+		return marathonUnhealthy
+	case a.TasksHealthy >= a.Instances:
+		return marathonHealthy
+	}
+	return marathonStarting
+}
+
+// containerType returns "docker" for apps run by the Docker containerizer,
+// "ucr" for apps run by the Universal Container Runtime and "none" for apps
+// without container definition.
+func (a MarathonApp) containerType() string {
+	if a.Container == nil {
+		return "none"
+	}
+	switch strings.ToUpper(a.Container.Type) {
+	case "DOCKER":
+		return "docker"
+	case "MESOS":
+		return "ucr"
+	}
+	return strings.ToLower(a.Container.Type)
+}
+
+// instances returns the configured instances of the pod, which Marathon
+// defaults to 1.
+func (p MarathonPod) instances() int {
+	if p.Scaling == nil || p.Scaling.Instances == nil {
+		return 1
+	}
+	return *p.Scaling.Instances
+}
+
+// Marathon implements a Reporter for the Marathon service
+type Marathon struct {
+	Report    *MarathonReport
+	Name      string
+	Endpoints []string
+	Method    string
+	Headers   map[string]string
+	Track     *analytics.Track
+	Error     []ReportError
+}
+
+func (m *Marathon) GetName() string {
+	return m.Name
+}
+
+// SetReport is not used, since the endpoint of a response decides how it is
+// read. See SetEndpointReport.
+func (m *Marathon) SetReport(body []byte) error {
+	return fmt.Errorf("%s needs to know the endpoint of a report", m.Name)
+}
+
+func (m *Marathon) SetEndpointReport(endpoint string, body []byte) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	if m.Report == nil {
+		m.Report = &MarathonReport{}
+	}
+
+	switch path := strings.TrimSuffix(u.Path, "/"); {
+	case strings.HasSuffix(path, "/v2/apps"):
+		var apps struct {
+			Apps []MarathonApp `json:"apps"`
+		}
+		if err := json.Unmarshal(body, &apps); err != nil {
+			return err
+		}
+		m.Report.Apps = apps.Apps
+	case strings.HasSuffix(path, "/v2/pods"):
+		return json.Unmarshal(body, &m.Report.Pods)
+	case strings.HasSuffix(path, "/v2/deployments"):
+		return json.Unmarshal(body, &m.Report.Deployments)
+	case strings.HasSuffix(path, "/v2/info"):
+		return json.Unmarshal(body, &m.Report.Info)
+	default:
+		return fmt.Errorf("unknown marathon endpoint %s", endpoint)
+	}
+	return nil
+}
+
+func (m *Marathon) GetReport() interface{} {
+	return m.Report
+}
+
+func (m *Marathon) AddHeaders(head map[string]string) {
+	for k, v := range head {
+		m.Headers[k] = v
+	}
+}
+
+func (m *Marathon) GetHeaders() map[string]string {
+	return m.Headers
+}
+
+// EndpointCount returns the number of endpoints Marathon needs.
+func (m *Marathon) EndpointCount() int {
+	return 4
+}
+
+func (m *Marathon) GetEndpoints() []string {
+	return m.Endpoints
+}
+
+func (m *Marathon) GetMethod() string {
+	return m.Method
+}
+
+func (m *Marathon) GetError() []ReportError {
+	return m.Error
+}
+
+func (m *Marathon) AppendError(err ReportError) {
+	m.Error = append(m.Error, err)
+}
+
+// TrackEvent returns the event of the Marathon track.
+func (m *Marathon) TrackEvent(config.Config) string {
+	return "marathon_track"
+}
+
+func (m *Marathon) SetTrack(c config.Config) error {
+	if m.Report == nil {
+		return fmt.Errorf("%s report is nil, bailing out", m.Name)
+	}
+
+	var (
+		appInstances = 0
+		podInstances = 0
+		byHealth     = map[string]int{
+			marathonSuspended:      0,
+			marathonNoHealthChecks: 0,
+			marathonUnhealthy:      0,
+			marathonHealthy:        0,
+			marathonStarting:       0,
+		}
+		byContainer = map[string]int{
+			"docker": 0,
+			"ucr":    0,
+			"none":   0,
+		}
+	)
+	for _, app := range m.Report.Apps {
+		appInstances += app.Instances
+		byHealth[app.healthState()]++
+		byContainer[app.containerType()]++
+	}
+	for _, pod := range m.Report.Pods {
+		podInstances += pod.instances()
+	}
+
+	properties := trackProperties(c, map[string]interface{}{
+		"marathon_version":  m.Report.Info.Version,
+		"app_count":         len(m.Report.Apps),
+		"app_instances":     appInstances,
+		"apps_by_health":    byHealth,
+		"apps_by_container": byContainer,
+		"pod_count":         len(m.Report.Pods),
+		"pod_instances":     podInstances,
+		"deployment_count":  len(m.Report.Deployments),
+	})
+
+	m.Track = &analytics.Track{
+		Event:       m.TrackEvent(c),
+		UserId:      c.CustomerKey,
+		AnonymousId: c.ClusterID,
+		Properties:  properties,
+	}
+	return nil
+}
+
+func (m *Marathon) GetTrack() *analytics.Track {
+	return m.Track
+}
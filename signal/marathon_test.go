@@ -0,0 +1,119 @@
+// +build unit
+
+package signal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/dcos/dcos-signal/config"
+)
+
+const (
+	mockMarathonApps = `
+{
+  "apps": [
+    {"id": "/kafka", "instances": 3, "container": {"type": "DOCKER"},
+     "healthChecks": [{"protocol": "HTTP"}], "tasksHealthy": 3, "tasksUnhealthy": 0},
+    {"id": "/spark", "instances": 2, "container": {"type": "MESOS"},
+     "healthChecks": [{"protocol": "HTTP"}], "tasksHealthy": 1, "tasksUnhealthy": 1},
+    {"id": "/sleep", "instances": 1},
+    {"id": "/suspended", "instances": 0, "container": {"type": "DOCKER"}}
+  ]
+}`
+	mockMarathonPods = `
+[
+  {"id": "/pod-a", "scaling": {"kind": "fixed", "instances": 2}, "containers": []},
+  {"id": "/pod-b", "containers": []}
+]`
+	mockMarathonDeployments = `[{"id": "97c136bf", "affectedApps": ["/kafka"], "affectedPods": []}]`
+	mockMarathonInfo        = `{"name": "marathon", "version": "1.8.0", "leader": "10.0.0.1:8080"}`
+)
+
+func mockJSON(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}
+}
+
+func testMarathonEndpoints() []string {
+	return []string{
+		fmt.Sprintf("%s/marathon/v2/apps", server.URL),
+		fmt.Sprintf("%s/marathon/v2/pods", server.URL),
+		fmt.Sprintf("%s/marathon/v2/deployments", server.URL),
+		fmt.Sprintf("%s/marathon/v2/info", server.URL),
+	}
+}
+
+func TestMarathonTrack(t *testing.T) {
+	c := config.DefaultConfig()
+	c.CustomerKey = "12345"
+	c.ClusterID = "anon"
+	c.MarathonURLs = testMarathonEndpoints()
+
+	marathon := findReporter(t, c, "marathon")
+	if marathon == nil {
+		t.Fatal("Expected marathon reporter when marathon_urls are set")
+	}
+
+	if err := runner(context.Background(), []Reporter{marathon}, c); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if errs := marathon.GetError(); len(errs) != 0 {
+		t.Fatal("Expected no errors, got", errs)
+	}
+
+	track := marathon.GetTrack()
+	if track.Event != "marathon_track" {
+		t.Error("Expected event marathon_track, got", track.Event)
+	}
+	if track.Properties["clusterId"] != "anon" {
+		t.Error("Expected clusterId anon, got", track.Properties["clusterId"])
+	}
+
+	for key, expected := range map[string]interface{}{
+		"marathon_version": "1.8.0",
+		"app_count":        4,
+		"app_instances":    6,
+		"pod_count":        2,
+		"pod_instances":    3,
+		"deployment_count": 1,
+	} {
+		if track.Properties[key] != expected {
+			t.Errorf("Expected %s to be %v, got %v", key, expected, track.Properties[key])
+		}
+	}
+
+	byHealth := track.Properties["apps_by_health"].(map[string]int)
+	for state, expected := range map[string]int{
+		marathonHealthy:        1,
+		marathonUnhealthy:      1,
+		marathonNoHealthChecks: 1,
+		marathonSuspended:      1,
+		marathonStarting:       0,
+	} {
+		if byHealth[state] != expected {
+			t.Errorf("Expected %d %s apps, got %d", expected, state, byHealth[state])
+		}
+	}
+
+	byContainer := track.Properties["apps_by_container"].(map[string]int)
+	if byContainer["docker"] != 2 || byContainer["ucr"] != 1 || byContainer["none"] != 1 {
+		t.Error("Expected 2 docker, 1 ucr and 1 app without container, got", byContainer)
+	}
+}
+
+func TestMarathonOptional(t *testing.T) {
+	if findReporter(t, config.DefaultConfig(), "marathon") != nil {
+		t.Error("Expected no marathon reporter without marathon_urls")
+	}
+}
+
+func TestMarathonUnknownEndpoint(t *testing.T) {
+	m := &Marathon{Name: "marathon"}
+	if err := m.SetEndpointReport("http://localhost/v2/queue", []byte("[]")); err == nil {
+		t.Error("Expected error for unknown endpoint, got nil")
+	}
+}
@@ -2,7 +2,11 @@ package signal
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/dcos/dcos-signal/config"
 	"gopkg.in/segmentio/analytics-go.v2"
@@ -10,23 +14,262 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// mesosMasterURL returns the URL of path on the Mesos master that c.MesosURLs
+// point to, so reporters of other master endpoints need no extra config.
+func mesosMasterURL(c config.Config, path string) (*url.URL, error) {
+	if len(c.MesosURLs) == 0 {
+		return nil, errors.New("no mesos_urls configured")
+	}
+	u, err := url.Parse(c.MesosURLs[0])
+	if err != nil {
+		return nil, err
+	}
+	// RawPath keeps paths like /registrar(1)/registry from being escaped.
+	return &url.URL{Scheme: u.Scheme, Host: u.Host, Path: path, RawPath: path}, nil
+}
+
+// mesosMasterEndpoints returns the URLs of paths on the Mesos master that
+// c.MesosURLs point to.
+func mesosMasterEndpoints(c config.Config, paths ...string) ([]string, error) {
+	var endpoints []string
+	for _, path := range paths {
+		u, err := mesosMasterURL(c, path)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, u.String())
+	}
+	return endpoints, nil
+}
+
 // Complete report used by signal service, composed of all requests
 type MesosReport struct {
-	Frameworks      []Framework `json:"frameworks"`
-	CPUTotal        float64     `json:"master/cpus_total"`
-	CPUUsed         float64     `json:"master/cpus_used"`
-	DiskTotal       float64     `json:"master/disk_total"`
-	DiskUsed        float64     `json:"master/disk_used"`
-	MemTotal        float64     `json:"master/mem_total"`
-	MemUsed         float64     `json:"master/mem_used"`
-	TaskCount       float64     `json:"master/tasks_running"`
-	FrameworkCount  float64     `json:"master/frameworks_active"`
-	AgentsConnected float64     `json:"master/slaves_connected"`
-	AgentsActive    float64     `json:"master/slaves_active"`
+	Frameworks          []Framework `json:"frameworks"`
+	CPUTotal            float64     `json:"master/cpus_total"`
+	CPUUsed             float64     `json:"master/cpus_used"`
+	DiskTotal           float64     `json:"master/disk_total"`
+	DiskUsed            float64     `json:"master/disk_used"`
+	MemTotal            float64     `json:"master/mem_total"`
+	MemUsed             float64     `json:"master/mem_used"`
+	TaskCount           float64     `json:"master/tasks_running"`
+	TasksStaging        float64     `json:"master/tasks_staging"`
+	TasksStarting       float64     `json:"master/tasks_starting"`
+	TasksKilling        float64     `json:"master/tasks_killing"`
+	TasksUnreachable    float64     `json:"master/tasks_unreachable"`
+	TasksFinished       float64     `json:"master/tasks_finished"`
+	TasksFailed         float64     `json:"master/tasks_failed"`
+	TasksKilled         float64     `json:"master/tasks_killed"`
+	TasksLost           float64     `json:"master/tasks_lost"`
+	TasksError          float64     `json:"master/tasks_error"`
+	TasksDropped        float64     `json:"master/tasks_dropped"`
+	TasksGone           float64     `json:"master/tasks_gone"`
+	TasksGoneByOperator float64     `json:"master/tasks_gone_by_operator"`
+	UptimeSecs          float64     `json:"master/uptime_secs"`
+	FrameworkCount      float64     `json:"master/frameworks_active"`
+	AgentsConnected     float64     `json:"master/slaves_connected"`
+	AgentsActive        float64     `json:"master/slaves_active"`
 }
 
+// Framework defines the fields of a framework in the /master/frameworks or
+// /master/state response
 type Framework struct {
-	Name string `json:"name"`
+	ID                 string            `json:"id"`
+	Name               string            `json:"name"`
+	Role               string            `json:"role"`
+	Roles              []string          `json:"roles"`
+	RegisteredTime     float64           `json:"registered_time"`
+	UsedResources      MesosResources    `json:"used_resources"`
+	OfferedResources   MesosResources    `json:"offered_resources"`
+	AllocatedResources []MesosResource   `json:"allocated_resources"`
+	Tasks              []json.RawMessage `json:"tasks"`
+}
+
+// MesosResources is the summary of scalar resources Mesos reports, e.g. in
+// used_resources.
+type MesosResources struct {
+	CPUs float64 `json:"cpus"`
+	Mem  float64 `json:"mem"`
+	Disk float64 `json:"disk"`
+	GPUs float64 `json:"gpus"`
+}
+
+// UnmarshalJSON reads resources either as the summary object Mesos uses in
+// most places or as a list of Resource protobufs, as in older quota responses.
+func (r *MesosResources) UnmarshalJSON(b []byte) error {
+	if trimmed := strings.TrimSpace(string(b)); strings.HasPrefix(trimmed, "[") {
+		var list []MesosResource
+		if err := json.Unmarshal(b, &list); err != nil {
+			return err
+		}
+		*r = MesosResources{}
+		for _, res := range list {
+			r.add(res.Name, res.value())
+		}
+		return nil
+	}
+
+	type summary MesosResources
+	return json.Unmarshal(b, (*summary)(r))
+}
+
+// add adds a scalar resource by its Mesos name, ignoring resources that are
+// not summarized.
+func (r *MesosResources) add(name string, value float64) {
+	switch name {
+	case "cpus":
+		r.CPUs += value
+	case "mem":
+		r.Mem += value
+	case "disk":
+		r.Disk += value
+	case "gpus":
+		r.GPUs += value
+	}
+}
+
+// MesosResource is a single resource as Mesos serializes the Resource
+// protobuf, e.g. in allocated_resources.
+type MesosResource struct {
+	Name   string `json:"name"`
+	Scalar *struct {
+		Value float64 `json:"value"`
+	} `json:"scalar"`
+	// Role is the pre-1.9 way of reserving a resource, "*" if unreserved.
+	Role string `json:"role"`
+	// Reservation is set for pre-1.9 dynamic reservations.
+	Reservation json.RawMessage `json:"reservation"`
+	// Reservations is the stack of reservations of a resource, empty if
+	// unreserved.
+	Reservations []struct {
+		Type string `json:"type"`
+		Role string `json:"role"`
+	} `json:"reservations"`
+}
+
+func (r MesosResource) reserved() bool {
+	return len(r.Reservations) > 0 || (r.Role != "" && r.Role != "*")
+}
+
+// dynamic reports whether a reserved resource was reserved through the
+// operator or scheduler API rather than statically by agent configuration.
+func (r MesosResource) dynamic() bool {
+	if n := len(r.Reservations); n > 0 {
+		return r.Reservations[n-1].Type == "DYNAMIC"
+	}
+	return len(r.Reservation) > 0 && string(r.Reservation) != "null"
+}
+
+// value returns the value of a scalar resource, 0 for other resources.
+func (r MesosResource) value() float64 {
+	if r.Scalar == nil {
+		return 0
+	}
+	return r.Scalar.Value
+}
+
+// FrameworkUsage is the resource usage of a framework, as sent in the
+// frameworks property of mesos_track.
+type FrameworkUsage struct {
+	Name           string         `json:"name"`
+	Role           string         `json:"role"`
+	RegisteredTime string         `json:"registered_time,omitempty"`
+	ActiveTasks    int            `json:"active_tasks"`
+	Used           MesosResources `json:"used"`
+	Offered        MesosResources `json:"offered"`
+	Reserved       MesosResources `json:"reserved"`
+}
+
+// usage returns the resource usage of the framework. Multi-role frameworks
+// report their roles comma separated.
+func (f Framework) usage() FrameworkUsage {
+	u := FrameworkUsage{
+		Name:        f.Name,
+		Role:        f.Role,
+		ActiveTasks: len(f.Tasks),
+		Used:        f.UsedResources,
+		Offered:     f.OfferedResources,
+	}
+	if len(f.Roles) > 0 {
+		u.Role = strings.Join(f.Roles, ",")
+	}
+	if f.RegisteredTime > 0 {
+		sec := int64(f.RegisteredTime)
+		nsec := int64((f.RegisteredTime - float64(sec)) * float64(time.Second))
+		u.RegisteredTime = time.Unix(sec, nsec).UTC().Format(time.RFC3339)
+	}
+	for _, r := range f.AllocatedResources {
+		if r.reserved() {
+			u.Reserved.add(r.Name, r.value())
+		}
+	}
+	return u
+}
+
+// taskStates returns the number of tasks currently in each non-terminal state.
+func (r *MesosReport) taskStates() map[string]float64 {
+	return map[string]float64{
+		"staging":     r.TasksStaging,
+		"starting":    r.TasksStarting,
+		"running":     r.TaskCount,
+		"killing":     r.TasksKilling,
+		"unreachable": r.TasksUnreachable,
+	}
+}
+
+// taskCounters returns the number of tasks that reached each terminal state
+// since the master started.
+func (r *MesosReport) taskCounters() map[string]float64 {
+	return map[string]float64{
+		"finished":         r.TasksFinished,
+		"failed":           r.TasksFailed,
+		"killed":           r.TasksKilled,
+		"lost":             r.TasksLost,
+		"error":            r.TasksError,
+		"dropped":          r.TasksDropped,
+		"gone":             r.TasksGone,
+		"gone_by_operator": r.TasksGoneByOperator,
+	}
+}
+
+// mesosTaskStateFile is the name of the previous task counters in StateDir.
+const mesosTaskStateFile = "mesos-task-counters.json"
+
+// masterSummary holds the part of the Mesos master /master/state-summary
+// response that tells which master is answering. Unlike /master/state, it
+// leaves out every task.
+type masterSummary struct {
+	Hostname string `json:"hostname"`
+}
+
+// mesosSummaryKey identifies the follow-up request for /master/state-summary.
+const mesosSummaryKey = "state-summary"
+
+// taskSnapshot is the task counters of a run, saved for the next one.
+type taskSnapshot struct {
+	Taken time.Time `json:"taken"`
+	// Host of the master the counters are from, which changes when another
+	// master is elected
+	Master     string             `json:"master"`
+	UptimeSecs float64            `json:"uptime_secs"`
+	Counters   map[string]float64 `json:"counters"`
+}
+
+// taskDeltas returns how much each counter grew since prev. Counters belong to
+// the master that counted them and start at 0 with it, so after a leader
+// election or a restart the current value is all that is known to have
+// happened since. Uptime alone does not tell, as a newly elected leader has
+// usually been up for longer than the previous one.
+func taskDeltas(prev, cur taskSnapshot) map[string]float64 {
+	restarted := cur.Master != prev.Master || cur.UptimeSecs < prev.UptimeSecs
+	deltas := make(map[string]float64, len(cur.Counters))
+	for name, value := range cur.Counters {
+		before, ok := prev.Counters[name]
+		if !ok || restarted || value < before {
+			before = 0
+		}
+		deltas[name] = value - before
+	}
+	return deltas
 }
 
 type Mesos struct {
@@ -35,82 +278,132 @@ type Mesos struct {
 	Method    string
 	Headers   map[string]string
 	Track     *analytics.Track
-	Error     []string
+	Error     []ReportError
 	Name      string
+	// SummaryURL is the master's /master/state-summary, which tells the task
+	// deltas which master the counters are from. It is only set if deltas are
+	// kept.
+	SummaryURL string
+	// Master is the host of the master the counters are from.
+	Master string
 }
 
-func (d *Mesos) getName() string {
+func (d *Mesos) GetName() string {
 	return d.Name
 }
 
-func (d *Mesos) setReport(body []byte) error {
+func (d *Mesos) SetReport(body []byte) error {
 	if err := json.Unmarshal(body, &d.Report); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (d *Mesos) getReport() interface{} {
+func (d *Mesos) GetReport() interface{} {
 	return d.Report
 }
 
-func (d *Mesos) addHeaders(head map[string]string) {
+func (d *Mesos) AddHeaders(head map[string]string) {
 	for k, v := range head {
 		d.Headers[k] = v
 	}
 }
-func (d *Mesos) getHeaders() map[string]string {
+func (d *Mesos) GetHeaders() map[string]string {
 	return d.Headers
 }
 
-func (d *Mesos) getEndpoints() []string {
-	if len(d.Endpoints) != 2 {
-		log.Errorf("Mesos needs 2 endpoints, got %d", len(d.Endpoints))
-	}
+// EndpointCount returns the number of endpoints Mesos needs.
+func (d *Mesos) EndpointCount() int {
+	return 2
+}
+
+func (d *Mesos) GetEndpoints() []string {
 	return d.Endpoints
 }
 
-func (d *Mesos) getMethod() string {
+func (d *Mesos) GetMethod() string {
 	return d.Method
 }
 
-func (d *Mesos) getError() []string {
+func (d *Mesos) GetError() []ReportError {
 	return d.Error
 }
 
-func (d *Mesos) appendError(err string) {
+func (d *Mesos) AppendError(err ReportError) {
 	d.Error = append(d.Error, err)
 }
 
-func (d *Mesos) setTrack(c config.Config) error {
+// FollowUps asks the master for its host once the counters are in, if task
+// deltas are kept.
+func (d *Mesos) FollowUps() []Request {
+	if d.SummaryURL == "" || d.Report == nil || d.Report.UptimeSecs == 0 {
+		return nil
+	}
+	return []Request{{
+		Key:      mesosSummaryKey,
+		Endpoint: d.SummaryURL,
+		Method:   "GET",
+		Headers:  d.Headers,
+	}}
+}
+
+func (d *Mesos) SetFollowUpReport(req Request, body []byte) error {
+	if req.Key != mesosSummaryKey {
+		return fmt.Errorf("unknown follow-up request %s", req.Key)
+	}
+	var summary masterSummary
+	if err := json.Unmarshal(body, &summary); err != nil {
+		return err
+	}
+	if summary.Hostname == "" {
+		return errors.New("master state summary has no hostname")
+	}
+	d.Master = summary.Hostname
+	return nil
+}
+
+// TrackEvent returns the event of the Mesos track.
+func (d *Mesos) TrackEvent(config.Config) string {
+	return "mesos_track"
+}
+
+func (d *Mesos) SetTrack(c config.Config) error {
 	if d.Report == nil {
 		return fmt.Errorf("%s report is nil, bailing out.", d.Name)
 	}
 
-	properties := map[string]interface{}{
-		"source":             "cluster",
-		"customerKey":        c.CustomerKey,
-		"environmentVersion": c.DCOSVersion,
-		"clusterId":          c.ClusterID,
-		"licenseId":          c.LicenseID,
-		"variant":            c.DCOSVariant,
-		"platform":           c.GenPlatform,
-		"provider":           c.GenProvider,
-		"frameworks":         d.Report.Frameworks,
-		"cpu_total":          d.Report.CPUTotal,
-		"cpu_used":           d.Report.CPUUsed,
-		"mem_total":          d.Report.MemTotal,
-		"mem_used":           d.Report.MemUsed,
-		"disk_total":         d.Report.DiskTotal,
-		"disk_used":          d.Report.DiskUsed,
-		"task_count":         d.Report.TaskCount,
-		"framework_count":    d.Report.FrameworkCount,
-		"agents_connected":   d.Report.AgentsConnected,
-		"agents_active":      d.Report.AgentsActive,
+	frameworks := make([]FrameworkUsage, 0, len(d.Report.Frameworks))
+	for _, f := range d.Report.Frameworks {
+		frameworks = append(frameworks, f.usage())
+	}
+
+	states := d.Report.taskStates()
+	for name, value := range d.Report.taskCounters() {
+		states[name] = value
+	}
+
+	properties := trackProperties(c, map[string]interface{}{
+		"frameworks":       frameworks,
+		"cpu_total":        d.Report.CPUTotal,
+		"cpu_used":         d.Report.CPUUsed,
+		"mem_total":        d.Report.MemTotal,
+		"mem_used":         d.Report.MemUsed,
+		"disk_total":       d.Report.DiskTotal,
+		"disk_used":        d.Report.DiskUsed,
+		"task_count":       d.Report.TaskCount,
+		"tasks_by_state":   states,
+		"framework_count":  d.Report.FrameworkCount,
+		"agents_connected": d.Report.AgentsConnected,
+		"agents_active":    d.Report.AgentsActive,
+	})
+
+	if deltas, since, ok := d.taskDeltas(c); ok {
+		properties["task_deltas"] = deltas
+		properties["task_deltas_seconds"] = since.Seconds()
 	}
 
 	d.Track = &analytics.Track{
-		Event:       "mesos_track",
+		Event:       d.TrackEvent(c),
 		UserId:      c.CustomerKey,
 		AnonymousId: c.ClusterID,
 		Properties:  properties,
@@ -118,13 +411,42 @@ func (d *Mesos) setTrack(c config.Config) error {
 	return nil
 }
 
-func (d *Mesos) getTrack() *analytics.Track {
+func (d *Mesos) GetTrack() *analytics.Track {
 	return d.Track
 }
 
-func (d *Mesos) sendTrack(c config.Config) error {
-	ac := CreateSegmentClient(c.SegmentKey, c.FlagVerbose)
-	defer ac.Close()
-	err := ac.Track(d.Track)
-	return err
+// taskDeltas compares the task counters of this run to the ones saved by the
+// previous run and saves the current ones for the next. It returns false if
+// there is nothing to compare to, if StateDir is not set or if the metrics
+// snapshot or the master's host was not pulled. Test runs only read the saved
+// counters.
+func (d *Mesos) taskDeltas(c config.Config) (map[string]float64, time.Duration, bool) {
+	// UptimeSecs is only 0 if the metrics snapshot is missing from the report.
+	if c.StateDir == "" || d.Report.UptimeSecs == 0 || d.Master == "" {
+		return nil, 0, false
+	}
+
+	cur := taskSnapshot{
+		Taken:      time.Now().UTC(),
+		Master:     d.Master,
+		UptimeSecs: d.Report.UptimeSecs,
+		Counters:   d.Report.taskCounters(),
+	}
+
+	var prev taskSnapshot
+	found, err := loadState(c.StateDir, mesosTaskStateFile, &prev)
+	if err != nil {
+		log.Warnf("Could not read previous task counters, skipping deltas: %s", err)
+	}
+
+	if !c.FlagTest {
+		if err := saveState(c.StateDir, mesosTaskStateFile, cur); err != nil {
+			log.Warnf("Could not save task counters for the next run: %s", err)
+		}
+	}
+
+	if !found || err != nil {
+		return nil, 0, false
+	}
+	return taskDeltas(prev, cur), cur.Taken.Sub(prev.Taken), true
 }
@@ -3,13 +3,24 @@
 package signal
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/dcos/dcos-signal/config"
 )
 
 var (
+	mockMasterSummary = `{
+		"hostname": "10.0.0.1",
+		"cluster": "test",
+		"slaves": [],
+		"frameworks": []
+	}`
+
 	testMesos = Mesos{
 		Endpoints: []string{
 			fmt.Sprintf("%s/frameworks", server.URL),
@@ -30,18 +41,18 @@ func testMesosTrack(t *testing.T) {
 	c.DCOSVariant = config.DCOSVariant{"test_variant"}
 
 	for _, e := range testMesos.Endpoints {
-		pullErr := PullReport(e, &testMesos, c)
+		pullErr := PullReport(context.Background(), e, &testMesos, c)
 		if pullErr != nil {
 			t.Error("Expected no errors pulling report from test server, got", pullErr)
 		}
 	}
 
-	setupErr := testMesos.setTrack(c)
+	setupErr := testMesos.SetTrack(c)
 	if setupErr != nil {
 		t.Error("Expected no errors setting up track, got", setupErr)
 	}
 
-	actualSegmentTrack := testMesos.getTrack()
+	actualSegmentTrack := testMesos.GetTrack()
 	if actualSegmentTrack.Event != "package_list" {
 		t.Error("Expected actualSegmentTrack.Event to be 'package_list', got ", actualSegmentTrack.Event)
 	}
@@ -86,8 +97,156 @@ func testMesosTrack(t *testing.T) {
 		t.Error("Expected environmenetVersion 'test_varsion', got ", actualSegmentTrack.Properties["environmentVersion"])
 	}
 
-	if len(actualSegmentTrack.Properties["frameworks"].([]Framework)) != 2 {
+	if len(actualSegmentTrack.Properties["frameworks"].([]FrameworkUsage)) != 2 {
 		t.Error("Expected 2 frameworks, got")
 	}
 
 }
+
+func TestMesosFrameworkUsage(t *testing.T) {
+	c := config.DefaultConfig()
+	m := &Mesos{
+		Name: "mesos",
+		Endpoints: []string{
+			fmt.Sprintf("%s/frameworks", server.URL),
+			fmt.Sprintf("%s/metrics/snapshot", server.URL),
+		},
+		Method: "GET",
+	}
+	if err := runner(context.Background(), []Reporter{m}, c); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	frameworks, ok := m.GetTrack().Properties["frameworks"].([]FrameworkUsage)
+	if !ok || len(frameworks) != 2 {
+		t.Fatal("Expected 2 framework usages, got", m.GetTrack().Properties["frameworks"])
+	}
+
+	marathon := frameworks[0]
+	if marathon.Name != "fooFramework1" || marathon.Role != "slave_public" {
+		t.Error("Expected fooFramework1 with role slave_public, got", marathon.Name, marathon.Role)
+	}
+	if marathon.RegisteredTime != "2019-01-14T10:00:00Z" {
+		t.Error("Expected registered_time 2019-01-14T10:00:00Z, got", marathon.RegisteredTime)
+	}
+	if marathon.ActiveTasks != 2 {
+		t.Error("Expected 2 active tasks, got", marathon.ActiveTasks)
+	}
+	if marathon.Used != (MesosResources{CPUs: 1.5, Mem: 256, Disk: 100, GPUs: 1}) {
+		t.Error("Expected used resources to be passed through, got", marathon.Used)
+	}
+	if marathon.Offered != (MesosResources{CPUs: 0.5}) {
+		t.Error("Expected offered resources to be passed through, got", marathon.Offered)
+	}
+	// Only the reserved cpus and the dynamically reserved disk count.
+	if marathon.Reserved != (MesosResources{CPUs: 1, Disk: 100}) {
+		t.Error("Expected 1 reserved cpu and 100 reserved disk, got", marathon.Reserved)
+	}
+
+	multiRole := frameworks[1]
+	if multiRole.Role != "a,b" {
+		t.Error("Expected roles a,b, got", multiRole.Role)
+	}
+	if multiRole.RegisteredTime != "" {
+		t.Error("Expected no registered_time, got", multiRole.RegisteredTime)
+	}
+}
+
+func testMesosMetrics() *Mesos {
+	return &Mesos{
+		Name:       "mesos",
+		Endpoints:  []string{fmt.Sprintf("%s/metrics/snapshot", server.URL)},
+		Method:     "GET",
+		SummaryURL: fmt.Sprintf("%s/master/state-summary", server.URL),
+	}
+}
+
+func TestMesosTaskStates(t *testing.T) {
+	m := testMesosMetrics()
+	if err := runner(context.Background(), []Reporter{m}, config.DefaultConfig()); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	states := m.GetTrack().Properties["tasks_by_state"].(map[string]float64)
+	for state, expected := range map[string]float64{
+		"running": 4,
+		"staging": 1,
+		"failed":  7,
+		"killed":  3,
+		"lost":    0,
+	} {
+		if states[state] != expected {
+			t.Errorf("Expected %v %s tasks, got %v", expected, state, states[state])
+		}
+	}
+
+	if _, ok := m.GetTrack().Properties["task_deltas"]; ok {
+		t.Error("Expected no task_deltas without state_dir")
+	}
+}
+
+func TestMesosTaskDeltas(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signal-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := config.DefaultConfig()
+	c.StateDir = dir
+
+	first := testMesosMetrics()
+	if err := runner(context.Background(), []Reporter{first}, c); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if _, ok := first.GetTrack().Properties["task_deltas"]; ok {
+		t.Error("Expected no task_deltas on the first run")
+	}
+
+	// Pretend the previous run saw fewer failures an hour ago.
+	var prev taskSnapshot
+	if found, err := loadState(dir, mesosTaskStateFile, &prev); !found || err != nil {
+		t.Fatal("Expected task counters to be saved, got", found, err)
+	}
+	prev.Taken = prev.Taken.Add(-time.Hour)
+	prev.Counters["failed"] = 2
+	if err := saveState(dir, mesosTaskStateFile, prev); err != nil {
+		t.Fatal(err)
+	}
+
+	second := testMesosMetrics()
+	if err := runner(context.Background(), []Reporter{second}, c); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	deltas, ok := second.GetTrack().Properties["task_deltas"].(map[string]float64)
+	if !ok {
+		t.Fatal("Expected task_deltas on the second run, got", second.GetTrack().Properties["task_deltas"])
+	}
+	if deltas["failed"] != 5 || deltas["killed"] != 0 {
+		t.Error("Expected 5 failed and 0 killed tasks since the last run, got", deltas)
+	}
+	if since := second.GetTrack().Properties["task_deltas_seconds"].(float64); since < 3600 {
+		t.Error("Expected task_deltas_seconds of at least an hour, got", since)
+	}
+}
+
+func TestTaskDeltasRestart(t *testing.T) {
+	prev := taskSnapshot{Master: "10.0.0.1", UptimeSecs: 7200, Counters: map[string]float64{"failed": 10, "lost": 1}}
+	cur := taskSnapshot{Master: "10.0.0.1", UptimeSecs: 60, Counters: map[string]float64{"failed": 3, "lost": 1}}
+
+	deltas := taskDeltas(prev, cur)
+	if deltas["failed"] != 3 || deltas["lost"] != 1 {
+		t.Error("Expected counters to count from 0 after a master restart, got", deltas)
+	}
+}
+
+func TestTaskDeltasFailover(t *testing.T) {
+	// The new leader has been up for longer and counted more than the old one.
+	prev := taskSnapshot{Master: "10.0.0.1", UptimeSecs: 60, Counters: map[string]float64{"failed": 3, "lost": 1}}
+	cur := taskSnapshot{Master: "10.0.0.2", UptimeSecs: 7200, Counters: map[string]float64{"failed": 10, "lost": 1}}
+
+	deltas := taskDeltas(prev, cur)
+	if deltas["failed"] != 10 || deltas["lost"] != 1 {
+		t.Error("Expected counters to count from 0 after a leader election, got", deltas)
+	}
+}
@@ -0,0 +1,206 @@
+package signal
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dcos/dcos-signal/config"
+	"gopkg.in/segmentio/analytics-go.v2"
+)
+
+// MetronomeJob defines the fields of a job in the Metronome /v1/jobs response,
+// with schedules, history and activeRuns embedded.
+type MetronomeJob struct {
+	ID        string `json:"id"`
+	Schedules []struct {
+		ID      string `json:"id"`
+		Enabled *bool  `json:"enabled"`
+	} `json:"schedules"`
+	ActiveRuns []json.RawMessage `json:"activeRuns"`
+	History    *struct {
+		SuccessCount           int            `json:"successCount"`
+		FailureCount           int            `json:"failureCount"`
+		SuccessfulFinishedRuns []MetronomeRun `json:"successfulFinishedRuns"`
+		FailedFinishedRuns     []MetronomeRun `json:"failedFinishedRuns"`
+	} `json:"history"`
+}
+
+// MetronomeRun defines a finished run in the history of a job
+type MetronomeRun struct {
+	ID         string `json:"id"`
+	CreatedAt  string `json:"createdAt"`
+	FinishedAt string `json:"finishedAt"`
+}
+
+// metronomeTimeLayouts are the timestamp layouts Metronome is known to use.
+var metronomeTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.000-0700",
+	"2006-01-02T15:04:05-0700",
+}
+
+// duration returns how long the run took, or false if that is not known.
+func (r MetronomeRun) duration() (time.Duration, bool) {
+	created, ok := parseMetronomeTime(r.CreatedAt)
+	if !ok {
+		return 0, false
+	}
+	finished, ok := parseMetronomeTime(r.FinishedAt)
+	if !ok || finished.Before(created) {
+		return 0, false
+	}
+	return finished.Sub(created), true
+}
+
+func parseMetronomeTime(s string) (time.Time, bool) {
+	for _, layout := range metronomeTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// scheduled reports whether the job has at least one enabled schedule.
+// Metronome enables schedules unless told otherwise.
+func (j MetronomeJob) scheduled() bool {
+	for _, s := range j.Schedules {
+		if s.Enabled == nil || *s.Enabled {
+			return true
+		}
+	}
+	return false
+}
+
+// Metronome implements a Reporter for the Metronome service
+type Metronome struct {
+	Report    []MetronomeJob
+	Name      string
+	Endpoints []string
+	Method    string
+	Headers   map[string]string
+	Track     *analytics.Track
+	Error     []ReportError
+}
+
+func (m *Metronome) GetName() string {
+	return m.Name
+}
+
+func (m *Metronome) SetReport(body []byte) error {
+	if err := json.Unmarshal(body, &m.Report); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *Metronome) GetReport() interface{} {
+	return m.Report
+}
+
+func (m *Metronome) AddHeaders(head map[string]string) {
+	for k, v := range head {
+		m.Headers[k] = v
+	}
+}
+
+func (m *Metronome) GetHeaders() map[string]string {
+	return m.Headers
+}
+
+// EndpointCount returns the number of endpoints Metronome needs.
+func (m *Metronome) EndpointCount() int {
+	return 1
+}
+
+func (m *Metronome) GetEndpoints() []string {
+	return m.Endpoints
+}
+
+func (m *Metronome) GetMethod() string {
+	return m.Method
+}
+
+func (m *Metronome) GetError() []ReportError {
+	return m.Error
+}
+
+func (m *Metronome) AppendError(err ReportError) {
+	m.Error = append(m.Error, err)
+}
+
+// TrackEvent returns the event of the Metronome track.
+func (m *Metronome) TrackEvent(config.Config) string {
+	return "metronome_track"
+}
+
+func (m *Metronome) SetTrack(c config.Config) error {
+	if m.Report == nil {
+		return fmt.Errorf("%s report is nil, bailing out", m.Name)
+	}
+
+	var (
+		scheduled, activeRuns         int
+		succeeded, failed             int
+		recentSucceeded, recentFailed int
+		durations                     []time.Duration
+	)
+	for _, job := range m.Report {
+		if job.scheduled() {
+			scheduled++
+		}
+		activeRuns += len(job.ActiveRuns)
+		if job.History == nil {
+			continue
+		}
+
+		succeeded += job.History.SuccessCount
+		failed += job.History.FailureCount
+		recentSucceeded += len(job.History.SuccessfulFinishedRuns)
+		recentFailed += len(job.History.FailedFinishedRuns)
+
+		for _, runs := range [][]MetronomeRun{job.History.SuccessfulFinishedRuns, job.History.FailedFinishedRuns} {
+			for _, run := range runs {
+				if d, ok := run.duration(); ok {
+					durations = append(durations, d)
+				}
+			}
+		}
+	}
+
+	// Ratios and averages are left out when there are no runs to base them on,
+	// so they cannot be mistaken for a real 0.
+	properties := trackProperties(c, map[string]interface{}{
+		"job_count":             len(m.Report),
+		"jobs_scheduled":        scheduled,
+		"jobs_unscheduled":      len(m.Report) - scheduled,
+		"active_runs":           activeRuns,
+		"runs_succeeded":        succeeded,
+		"runs_failed":           failed,
+		"recent_runs_succeeded": recentSucceeded,
+		"recent_runs_failed":    recentFailed,
+	})
+	if recent := recentSucceeded + recentFailed; recent > 0 {
+		properties["recent_success_ratio"] = float64(recentSucceeded) / float64(recent)
+	}
+	if len(durations) > 0 {
+		var total time.Duration
+		for _, d := range durations {
+			total += d
+		}
+		properties["avg_run_duration_seconds"] = (total / time.Duration(len(durations))).Seconds()
+	}
+
+	m.Track = &analytics.Track{
+		Event:       m.TrackEvent(c),
+		UserId:      c.CustomerKey,
+		AnonymousId: c.ClusterID,
+		Properties:  properties,
+	}
+	return nil
+}
+
+func (m *Metronome) GetTrack() *analytics.Track {
+	return m.Track
+}
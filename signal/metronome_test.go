@@ -0,0 +1,100 @@
+// +build unit
+
+package signal
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/dcos/dcos-signal/config"
+)
+
+const mockMetronomeJobs = `
+[
+  {"id": "backup", "run": {"cmd": "backup.sh"},
+   "schedules": [{"id": "nightly", "cron": "0 2 * * *", "enabled": true}],
+   "activeRuns": [{"id": "20190114023000abcde", "status": "ACTIVE"}],
+   "history": {
+     "successCount": 10, "failureCount": 2,
+     "successfulFinishedRuns": [
+       {"id": "1", "createdAt": "2019-01-13T02:00:00.000+0000", "finishedAt": "2019-01-13T02:01:00.000+0000"},
+       {"id": "2", "createdAt": "2019-01-12T02:00:00.000+0000", "finishedAt": "2019-01-12T02:03:00.000+0000"}
+     ],
+     "failedFinishedRuns": [
+       {"id": "3", "createdAt": "2019-01-11T02:00:00.000+0000", "finishedAt": "2019-01-11T02:02:00.000+0000"}
+     ]}},
+  {"id": "paused", "run": {"cmd": "sleep 1"},
+   "schedules": [{"id": "hourly", "cron": "0 * * * *", "enabled": false}],
+   "activeRuns": [],
+   "history": {"successCount": 0, "failureCount": 1, "successfulFinishedRuns": [],
+     "failedFinishedRuns": [{"id": "4", "createdAt": "2019-01-10T02:00:00.000+0000"}]}},
+  {"id": "adhoc", "run": {"cmd": "echo hi"}, "schedules": [], "activeRuns": []}
+]`
+
+func TestMetronomeTrack(t *testing.T) {
+	c := config.DefaultConfig()
+	c.CustomerKey = "12345"
+	c.ClusterID = "anon"
+	c.MetronomeURLs = []string{
+		fmt.Sprintf("%s/metronome/v1/jobs?embed=history&embed=schedules&embed=activeRuns", server.URL),
+	}
+
+	metronome := findReporter(t, c, "metronome")
+	if metronome == nil {
+		t.Fatal("Expected metronome reporter when metronome_urls are set")
+	}
+
+	if err := runner(context.Background(), []Reporter{metronome}, c); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if errs := metronome.GetError(); len(errs) != 0 {
+		t.Fatal("Expected no errors, got", errs)
+	}
+
+	track := metronome.GetTrack()
+	if track.Event != "metronome_track" {
+		t.Error("Expected event metronome_track, got", track.Event)
+	}
+
+	// The failed run of "paused" never finished, so only three runs have a
+	// duration: 1, 3 and 2 minutes.
+	for key, expected := range map[string]interface{}{
+		"job_count":                3,
+		"jobs_scheduled":           1,
+		"jobs_unscheduled":         2,
+		"active_runs":              1,
+		"runs_succeeded":           10,
+		"runs_failed":              3,
+		"recent_runs_succeeded":    2,
+		"recent_runs_failed":       2,
+		"recent_success_ratio":     0.5,
+		"avg_run_duration_seconds": 120.0,
+	} {
+		if track.Properties[key] != expected {
+			t.Errorf("Expected %s to be %v, got %v", key, expected, track.Properties[key])
+		}
+	}
+}
+
+func TestMetronomeNoRuns(t *testing.T) {
+	c := config.DefaultConfig()
+	m := &Metronome{Name: "metronome"}
+	if err := m.SetReport([]byte(`[{"id": "adhoc", "schedules": []}]`)); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if err := m.SetTrack(c); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	for _, key := range []string{"recent_success_ratio", "avg_run_duration_seconds"} {
+		if _, ok := m.GetTrack().Properties[key]; ok {
+			t.Errorf("Expected no %s without finished runs", key)
+		}
+	}
+}
+
+func TestMetronomeOptional(t *testing.T) {
+	if findReporter(t, config.DefaultConfig(), "metronome") != nil {
+		t.Error("Expected no metronome reporter without metronome_urls")
+	}
+}
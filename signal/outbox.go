@@ -0,0 +1,215 @@
+package signal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dcos/dcos-signal/config"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/segmentio/analytics-go.v2"
+)
+
+// outboxEntry is a track that could not be delivered to a sink.
+type outboxEntry struct {
+	Sink     string           `json:"sink"`
+	Reporter string           `json:"reporter"`
+	Created  time.Time        `json:"created"`
+	Track    *analytics.Track `json:"track"`
+
+	file string
+	size int64
+}
+
+// outbox spools undelivered tracks in a directory, one file per entry. Entries
+// are written to a temporary file and renamed into place, so a crash never
+// leaves a partial entry behind, and are never modified after that: they are
+// only removed once delivered or once they exceed the age or size cap.
+type outbox struct {
+	dir      string
+	maxBytes int64
+	maxAge   time.Duration
+	now      func() time.Time
+}
+
+const outboxTempPrefix = ".tmp-"
+
+// newOutbox returns the outbox configured in c, or nil if it is disabled.
+func newOutbox(c config.Config) (*outbox, error) {
+	if c.OutboxDir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(c.OutboxDir, 0700); err != nil {
+		return nil, err
+	}
+	return &outbox{
+		dir:      c.OutboxDir,
+		maxBytes: c.OutboxMaxBytes,
+		maxAge:   c.OutboxMaxAge.Duration,
+		now:      time.Now,
+	}, nil
+}
+
+// put spools a track that could not be delivered to the named sink.
+func (o *outbox) put(sink, reporter string, track *analytics.Track) error {
+	entry := outboxEntry{
+		Sink:     sink,
+		Reporter: reporter,
+		Created:  o.now(),
+		Track:    track,
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(o.dir, outboxTempPrefix)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	// Names sort by creation time, so entries are redelivered oldest first.
+	name := fmt.Sprintf("%020d-%s.json", entry.Created.UnixNano(), uuid.New())
+	if err := os.Rename(tmp.Name(), filepath.Join(o.dir, name)); err != nil {
+		return err
+	}
+	return syncDir(o.dir)
+}
+
+// entries returns all spooled entries, oldest first. Entries that cannot be
+// read are removed.
+func (o *outbox) entries() ([]*outboxEntry, error) {
+	files, err := ioutil.ReadDir(o.dir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+
+	var entries []*outboxEntry
+	for _, f := range files {
+		if f.IsDir() || strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(o.dir, f.Name())
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		entry := &outboxEntry{file: path, size: f.Size()}
+		if err := json.Unmarshal(b, entry); err != nil || entry.Track == nil {
+			log.Warnf("Removing unreadable outbox entry %s", path)
+			os.Remove(path)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (o *outbox) remove(entry *outboxEntry) error {
+	return os.Remove(entry.file)
+}
+
+// redeliver sends every spooled entry to the sink it failed on and removes the
+// ones that got through. Entries for sinks that are no longer configured stay
+// until they expire.
+func (o *outbox) redeliver(sinks []Sink) {
+	entries, err := o.entries()
+	if err != nil {
+		log.Errorf("error reading outbox: %s", err)
+		return
+	}
+
+	byName := make(map[string]Sink)
+	for _, s := range sinks {
+		byName[s.Name()] = s
+	}
+
+	for _, entry := range entries {
+		sink, ok := byName[entry.Sink]
+		if !ok {
+			continue
+		}
+		if err := sink.Send(entry.Reporter, entry.Track); err != nil {
+			log.Warnf("Redelivering %s track %s to %s failed: %s", entry.Reporter, entry.Track.MessageId, entry.Sink, err)
+			continue
+		}
+		log.Infof("Redelivered %s track %s to %s", entry.Reporter, entry.Track.MessageId, entry.Sink)
+		if err := o.remove(entry); err != nil {
+			log.Errorf("error removing outbox entry: %s", err)
+		}
+	}
+}
+
+// prune removes entries older than maxAge, then the oldest entries until the
+// outbox fits in maxBytes. It also cleans up temporary files left behind by a
+// crash during put.
+func (o *outbox) prune() error {
+	files, err := ioutil.ReadDir(o.dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if strings.HasPrefix(f.Name(), outboxTempPrefix) && o.now().Sub(f.ModTime()) > time.Hour {
+			os.Remove(filepath.Join(o.dir, f.Name()))
+		}
+	}
+
+	entries, err := o.entries()
+	if err != nil {
+		return err
+	}
+
+	var (
+		kept  []*outboxEntry
+		total int64
+	)
+	for _, entry := range entries {
+		if o.maxAge > 0 && o.now().Sub(entry.Created) > o.maxAge {
+			log.Warnf("Dropping %s track %s for %s, older than %s", entry.Reporter, entry.Track.MessageId, entry.Sink, o.maxAge)
+			o.remove(entry)
+			continue
+		}
+		kept = append(kept, entry)
+		total += entry.size
+	}
+
+	for len(kept) > 0 && o.maxBytes > 0 && total > o.maxBytes {
+		entry := kept[0]
+		log.Warnf("Dropping %s track %s for %s, outbox exceeds %d bytes", entry.Reporter, entry.Track.MessageId, entry.Sink, o.maxBytes)
+		o.remove(entry)
+		total -= entry.size
+		kept = kept[1:]
+	}
+	return nil
+}
+
+// syncDir flushes directory metadata, making a preceding rename durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
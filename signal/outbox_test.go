@@ -0,0 +1,176 @@
+// +build unit
+
+package signal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-signal/config"
+	"gopkg.in/segmentio/analytics-go.v2"
+)
+
+// recordingSink remembers the tracks sent to it, or fails every send.
+type recordingSink struct {
+	name   string
+	fail   bool
+	tracks []*analytics.Track
+}
+
+func (s *recordingSink) Name() string { return s.name }
+
+func (s *recordingSink) Send(reporter string, track *analytics.Track) error {
+	if s.fail {
+		return errors.New("sink unavailable")
+	}
+	s.tracks = append(s.tracks, track)
+	return nil
+}
+
+func (s *recordingSink) Close() error { return nil }
+
+func testOutbox(t *testing.T) (*outbox, func()) {
+	dir, err := ioutil.TempDir("", "outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := config.DefaultConfig()
+	c.OutboxDir = filepath.Join(dir, "outbox")
+	ob, err := newOutbox(c)
+	if err != nil {
+		t.Fatal("Expected nil error creating outbox, got", err)
+	}
+	return ob, func() { os.RemoveAll(dir) }
+}
+
+func TestOutboxDisabled(t *testing.T) {
+	if ob, err := newOutbox(config.DefaultConfig()); ob != nil || err != nil {
+		t.Error("Expected no outbox without a directory, got", ob, err)
+	}
+}
+
+func TestOutboxRedeliver(t *testing.T) {
+	ob, cleanup := testOutbox(t)
+	defer cleanup()
+
+	ob.put("segment", "mesos", &analytics.Track{Event: "mesos_track", Message: analytics.Message{MessageId: "1"}})
+	ob.put("webhook:gone", "cosmos", &analytics.Track{Event: "package_list", Message: analytics.Message{MessageId: "2"}})
+	ob.put("segment", "diagnostics", &analytics.Track{Event: "health", Message: analytics.Message{MessageId: "3"}})
+
+	failing := &recordingSink{name: "segment", fail: true}
+	ob.redeliver([]Sink{failing})
+	if entries, _ := ob.entries(); len(entries) != 3 {
+		t.Error("Expected entries to stay after failed redelivery, got", len(entries))
+	}
+
+	segment := &recordingSink{name: "segment"}
+	ob.redeliver([]Sink{segment})
+	if len(segment.tracks) != 2 || segment.tracks[0].MessageId != "1" || segment.tracks[1].MessageId != "3" {
+		t.Error("Expected tracks 1 and 3 to be redelivered in order, got", segment.tracks)
+	}
+
+	entries, _ := ob.entries()
+	if len(entries) != 1 || entries[0].Sink != "webhook:gone" {
+		t.Error("Expected only the entry for the unconfigured sink to stay, got", entries)
+	}
+}
+
+func TestOutboxPrune(t *testing.T) {
+	ob, cleanup := testOutbox(t)
+	defer cleanup()
+
+	now := time.Now()
+	ob.now = func() time.Time { return now.Add(-30 * 24 * time.Hour) }
+	ob.put("segment", "mesos", &analytics.Track{Event: "expired"})
+	ob.now = func() time.Time { return now.Add(-time.Hour) }
+	ob.put("segment", "mesos", &analytics.Track{Event: "oldest"})
+	ob.now = func() time.Time { return now }
+	ob.put("segment", "mesos", &analytics.Track{Event: "newest"})
+
+	entries, _ := ob.entries()
+	ob.maxBytes = entries[2].size
+	if err := ob.prune(); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	entries, _ = ob.entries()
+	if len(entries) != 1 || entries[0].Track.Event != "newest" {
+		t.Error("Expected only the newest entry to be kept, got", entries)
+	}
+}
+
+func TestOutboxIgnoresTempFiles(t *testing.T) {
+	ob, cleanup := testOutbox(t)
+	defer cleanup()
+
+	ioutil.WriteFile(filepath.Join(ob.dir, outboxTempPrefix+"crashed"), []byte("{"), 0600)
+	ioutil.WriteFile(filepath.Join(ob.dir, "garbage.json"), []byte("{"), 0600)
+
+	if entries, err := ob.entries(); err != nil || len(entries) != 0 {
+		t.Error("Expected no entries and no error, got", entries, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(ob.dir, "garbage.json")); !os.IsNotExist(err) {
+		t.Error("Expected unreadable entry to be removed")
+	}
+}
+
+func TestExecuteReportersSpoolsFailedTracks(t *testing.T) {
+	ob, cleanup := testOutbox(t)
+	defer cleanup()
+
+	var (
+		up       = false
+		received []analytics.Track
+	)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up {
+			http.Error(w, http.StatusText(503), 503)
+			return
+		}
+		var track analytics.Track
+		json.NewDecoder(r.Body).Decode(&track)
+		received = append(received, track)
+	}))
+	defer hook.Close()
+
+	c := config.DefaultConfig()
+	c.ClusterID = "anon"
+	c.OutboxDir = ob.dir
+	c.Sinks = []config.SinkConfig{{Type: "webhook", URL: hook.URL}}
+	newCosmos := func() []Reporter {
+		return []Reporter{&Cosmos{
+			Name:      "cosmos",
+			Endpoints: []string{fmt.Sprintf("%s/package/list", server.URL)},
+			Method:    "POST",
+		}}
+	}
+
+	executeReporters(context.Background(), newCosmos(), c)
+	entries, _ := ob.entries()
+	if len(entries) != 1 {
+		t.Fatal("Expected failed track in outbox, got", len(entries))
+	}
+	spooledID := entries[0].Track.MessageId
+	if spooledID == "" {
+		t.Error("Expected spooled track to have a message ID")
+	}
+
+	up = true
+	executeReporters(context.Background(), newCosmos(), c)
+	if entries, _ := ob.entries(); len(entries) != 0 {
+		t.Error("Expected outbox to be empty after redelivery, got", len(entries))
+	}
+	if len(received) != 2 || received[0].MessageId != spooledID || received[1].MessageId == spooledID {
+		t.Error("Expected spooled track followed by a new one, got", received)
+	}
+}
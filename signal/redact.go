@@ -0,0 +1,212 @@
+package signal
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/dcos/dcos-signal/config"
+)
+
+// Placeholders that scrubbed text is replaced with
+const (
+	redactedIP     = "<ip>"
+	redactedHost   = "<host>"
+	redactedSecret = "<redacted>"
+)
+
+var (
+	ipv4Pattern = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	// Candidates only; matches are checked with net.ParseIP so times like
+	// 12:30:45 are left alone.
+	ipv6Pattern = regexp.MustCompile(`[0-9a-fA-F]{0,4}(?::[0-9a-fA-F]{0,4}){2,7}`)
+
+	// Names of three or more labels ending in a letter-only label, so that
+	// unit names like dcos-mesos-master.service and versions are left alone.
+	hostnamePattern = regexp.MustCompile(`\b(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?\.){2,}[a-zA-Z]{2,}\b`)
+
+	// Values following a key that names a secret, e.g. password=hunter2 or
+	// "token": "abc", keep the key and lose the value.
+	secretAssignmentPattern = regexp.MustCompile(`(?i)((?:password|passwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key|credentials?|authorization)["']?\s*[:=]\s*["']?)[^\s"',;]+`)
+	secretPatterns          = []*regexp.Regexp{
+		// Authorization header values
+		regexp.MustCompile(`(?i)\b(?:bearer|basic) +[A-Za-z0-9._~+/=-]{8,}`),
+		// JSON web tokens
+		regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+		// AWS access key IDs
+		regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	}
+	// Candidates only; long strings like keys also match paths and IDs, so
+	// matches are checked with looksRandom.
+	longTokenPattern = regexp.MustCompile(`\b[A-Za-z0-9+/_-]{32,}={0,2}`)
+)
+
+// looksRandom reports whether s mixes upper and lower case letters and digits
+// like base64 encoded keys do. Paths, unit names and hex IDs do not.
+func looksRandom(s string) bool {
+	var upper, lower, digits int
+	for _, c := range s {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper++
+		case c >= 'a' && c <= 'z':
+			lower++
+		case c >= '0' && c <= '9':
+			digits++
+		}
+	}
+	return upper >= 2 && lower >= 2 && digits >= 1
+}
+
+// redactor scrubs identifying and secret looking text from journal excerpts
+// before they leave the cluster.
+type redactor struct {
+	cfg      config.RedactConfig
+	hosts    []string
+	patterns []*regexp.Regexp
+}
+
+// newRedactor returns a redactor for cfg that also scrubs the given known
+// hostnames. It fails if a custom pattern does not compile, rather than
+// sending text it was asked to scrub.
+func newRedactor(cfg config.RedactConfig, hosts []string) (*redactor, error) {
+	r := &redactor{cfg: cfg}
+	for _, p := range cfg.Patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %s", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	// Longest first, so a host is not partly replaced by one of its prefixes.
+	for _, h := range hosts {
+		if h != "" {
+			r.hosts = append(r.hosts, h)
+		}
+	}
+	sort.Slice(r.hosts, func(i, j int) bool { return len(r.hosts[i]) > len(r.hosts[j]) })
+	return r, nil
+}
+
+// scrub returns s with everything the redactor is configured to remove
+// replaced by placeholders.
+func (r *redactor) scrub(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, redactedSecret)
+	}
+	if r.cfg.Secrets {
+		for _, re := range secretPatterns {
+			s = re.ReplaceAllString(s, redactedSecret)
+		}
+		s = longTokenPattern.ReplaceAllStringFunc(s, func(m string) string {
+			if !looksRandom(m) {
+				return m
+			}
+			return redactedSecret
+		})
+		s = secretAssignmentPattern.ReplaceAllString(s, "${1}"+redactedSecret)
+	}
+	if r.cfg.Hostnames {
+		for _, h := range r.hosts {
+			s = strings.Replace(s, h, redactedHost, -1)
+		}
+		s = hostnamePattern.ReplaceAllString(s, redactedHost)
+	}
+	if r.cfg.IPs {
+		s = ipv4Pattern.ReplaceAllString(s, redactedIP)
+		s = ipv6Pattern.ReplaceAllStringFunc(s, func(m string) string {
+			if net.ParseIP(m) == nil {
+				return m
+			}
+			return redactedIP
+		})
+	}
+	return s
+}
+
+// excerpt returns the last max bytes of s, where journal output usually has
+// the error, cut at a character boundary. There is no excerpt unless max is
+// positive.
+func excerpt(s string, max int) string {
+	if max <= 0 {
+		return ""
+	}
+	s = strings.TrimSpace(s)
+	if len(s) <= max {
+		return s
+	}
+	cut := len(s) - max
+	for cut < len(s) && !utf8.RuneStart(s[cut]) {
+		cut++
+	}
+	return "..." + s[cut:]
+}
+
+// hashIP returns a pseudonym for ip that is stable for the same key, so the
+// same node can be recognized across units and runs without sending its
+// address.
+func hashIP(key []byte, ip string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// nodeHashKeyFile is the name of the key node IPs are hashed with in StateDir.
+const nodeHashKeyFile = "node-hash-key.json"
+
+// The key node IPs are hashed with if there is no StateDir
+var (
+	processHashKeyOnce sync.Once
+	processHashKey     []byte
+	processHashKeyErr  error
+)
+
+// newHashKey returns a new random key for hashIP.
+func newHashKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// nodeHashKey returns the key node IPs are hashed with. It is random, made once
+// per install and kept in StateDir, and never sent, so that nothing in a track
+// helps guess the addresses behind the hashes. Without StateDir the key, and so
+// every hash, changes whenever signal starts. Test runs do not save a new key.
+func nodeHashKey(c config.Config) ([]byte, error) {
+	if c.StateDir == "" {
+		processHashKeyOnce.Do(func() {
+			processHashKey, processHashKeyErr = newHashKey()
+		})
+		return processHashKey, processHashKeyErr
+	}
+
+	var key []byte
+	found, err := loadState(c.StateDir, nodeHashKeyFile, &key)
+	if err != nil {
+		return nil, err
+	}
+	if found && len(key) > 0 {
+		return key, nil
+	}
+
+	if key, err = newHashKey(); err != nil {
+		return nil, err
+	}
+	if !c.FlagTest {
+		if err := saveState(c.StateDir, nodeHashKeyFile, key); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
@@ -0,0 +1,110 @@
+package signal
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/dcos/dcos-signal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// ReporterFactory builds a reporter from the signal configuration. It returns
+// a nil Reporter if the reporter does not apply to this configuration, e.g.
+// because its optional endpoints are not configured.
+type ReporterFactory func(config.Config) (Reporter, error)
+
+var (
+	registryMu    sync.Mutex
+	registry      = make(map[string]ReporterFactory)
+	registryOrder []string
+)
+
+// RegisterReporter makes a reporter available to every run under the given
+// name. It is meant to be called from the init function of a package that is
+// compiled into signal, and panics if the name is already taken. Reporters run
+// in the order they were registered and can be turned off by name in the
+// "reporters" section of the signal config file.
+func RegisterReporter(name string, factory ReporterFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic("signal: RegisterReporter factory is nil for " + name)
+	}
+	if _, dup := registry[name]; dup {
+		panic("signal: RegisterReporter called twice for " + name)
+	}
+	registry[name] = factory
+	registryOrder = append(registryOrder, name)
+}
+
+// RegisteredReporters returns the names of all registered reporters in the
+// order they run.
+func RegisteredReporters() []string {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	return append([]string(nil), registryOrder...)
+}
+
+func makeReporters(c config.Config) ([]Reporter, error) {
+	var (
+		reporters []Reporter
+		seen      = make(map[string]bool)
+	)
+	for _, name := range RegisteredReporters() {
+		if !c.ReporterEnabled(name) {
+			continue
+		}
+
+		registryMu.Lock()
+		factory := registry[name]
+		registryMu.Unlock()
+
+		r, err := factory(c)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create reporter %s: %s", name, err)
+		}
+		if r == nil {
+			continue
+		}
+		// A reporter with the wrong number of endpoints is not gathered but
+		// still sends its error, the validate command fails on it. Reporters
+		// without any endpoints are rejected by the runner.
+		if ec, ok := r.(EndpointCounter); ok {
+			if n := len(r.GetEndpoints()); n > 0 && n != ec.EndpointCount() {
+				err := fmt.Errorf("reporter %s has %d endpoints, needs %d", name, n, ec.EndpointCount())
+				log.Error(err)
+				r.AppendError(ReportError{
+					Phase:   phaseConfig,
+					Class:   classConfig,
+					Message: err.Error(),
+				})
+			}
+		}
+		r.AddHeaders(c.ExtraHeaders)
+		reporters = append(reporters, r)
+		seen[name] = true
+	}
+
+	// Reporters defined in the config file run after the registered ones. They
+	// may share a name with a registered reporter that does not run, like
+	// marathon without marathon_urls.
+	for _, gc := range c.GenericReporters {
+		if !c.ReporterEnabled(gc.Name) {
+			continue
+		}
+		if seen[gc.Name] {
+			return nil, fmt.Errorf("generic reporter %s does not have a unique name", gc.Name)
+		}
+		seen[gc.Name] = true
+
+		g, err := newGeneric(gc)
+		if err != nil {
+			return nil, err
+		}
+		g.AddHeaders(c.ExtraHeaders)
+		reporters = append(reporters, g)
+	}
+
+	return reporters, nil
+}
@@ -0,0 +1,95 @@
+// +build unit
+
+package signal
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dcos/dcos-signal/config"
+)
+
+// withTestReporter registers a reporter for the duration of a test.
+func withTestReporter(name string, factory ReporterFactory) func() {
+	RegisterReporter(name, factory)
+	return func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+		delete(registry, name)
+		registryOrder = registryOrder[:len(registryOrder)-1]
+	}
+}
+
+func TestRegisterReporter(t *testing.T) {
+	defer withTestReporter("acme", func(c config.Config) (Reporter, error) {
+		return &Mesos{Name: "acme", Headers: map[string]string{}}, nil
+	})()
+
+	c := config.DefaultConfig()
+	c.ExtraHeaders = map[string]string{"Authorization": "token=foo"}
+	c.Reporters = map[string]bool{"cosmos": false}
+
+	reporters, err := makeReporters(c)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	var names []string
+	for _, r := range reporters {
+		names = append(names, r.GetName())
+	}
+	if len(names) != 3 || names[0] != "diagnostics" || names[1] != "mesos" || names[2] != "acme" {
+		t.Error("Expected diagnostics, mesos and acme in order, got", names)
+	}
+
+	if reporters[2].GetHeaders()["Authorization"] != "token=foo" {
+		t.Error("Expected extra headers on registered reporter, got", reporters[2].GetHeaders())
+	}
+}
+
+func TestRegisterReporterFactoryError(t *testing.T) {
+	defer withTestReporter("broken", func(c config.Config) (Reporter, error) {
+		return nil, errors.New("not configured")
+	})()
+
+	if _, err := makeReporters(config.DefaultConfig()); err == nil {
+		t.Error("Expected error from failing factory, got nil")
+	}
+}
+
+func TestMakeReportersEndpointCount(t *testing.T) {
+	c := config.DefaultConfig()
+	c.MesosURLs = []string{"http://localhost/frameworks"}
+
+	mesos := findReporter(t, c, "mesos")
+	if mesos == nil {
+		t.Fatal("Expected mesos reporter with the wrong number of endpoints")
+	}
+	errs := mesos.GetError()
+	if len(errs) != 1 || errs[0].Phase != phaseConfig || errs[0].Message != "reporter mesos has 1 endpoints, needs 2" {
+		t.Fatal("Expected config error for mesos endpoints, got", errs)
+	}
+
+	// The reporter is not gathered, and only sends its errors.
+	if err := runner(context.Background(), []Reporter{mesos}, c); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if mesos.GetReport() != (*MesosReport)(nil) || mesos.GetTrack() != nil {
+		t.Error("Expected mesos not to be gathered, got", mesos.GetReport())
+	}
+
+	c.MesosURLs = append(c.MesosURLs, "http://localhost/metrics/snapshot")
+	if errs := findReporter(t, c, "mesos").GetError(); len(errs) != 0 {
+		t.Error("Expected no errors, got", errs)
+	}
+}
+
+func TestRegisterReporterTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic registering mesos twice")
+		}
+	}()
+	RegisterReporter("mesos", func(c config.Config) (Reporter, error) { return nil, nil })
+}
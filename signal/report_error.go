@@ -0,0 +1,98 @@
+package signal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Phases of gathering a report in which a ReportError can occur.
+const (
+	phaseConfig = "config"
+	phasePull   = "pull"
+	phaseParse  = "parse"
+	phaseTrack  = "track"
+)
+
+// Classes of ReportError, so analytics can group failures without parsing
+// messages.
+const (
+	classTimeout    = "timeout"
+	classNetwork    = "network"
+	classHTTPStatus = "http_status"
+	classAuth       = "auth"
+	classDecode     = "decode"
+	classIncomplete = "incomplete"
+	classConfig     = "config"
+	classUnknown    = "unknown"
+)
+
+// ReportError describes a failure while gathering a report. Reporters send the
+// errors of a run in the "errors" property of their track, next to whatever
+// data they did collect.
+type ReportError struct {
+	Endpoint   string `json:"endpoint,omitempty"`
+	Phase      string `json:"phase"`
+	Class      string `json:"class"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Message    string `json:"message"`
+}
+
+func (e ReportError) String() string {
+	if e.Endpoint == "" {
+		return fmt.Sprintf("%s: %s", e.Phase, e.Message)
+	}
+	return fmt.Sprintf("%s %s: %s", e.Phase, e.Endpoint, e.Message)
+}
+
+// hasConfigError reports whether r was set up with an error that keeps it from
+// being gathered.
+func hasConfigError(r Reporter) bool {
+	for _, err := range r.GetError() {
+		if err.Phase == phaseConfig {
+			return true
+		}
+	}
+	return false
+}
+
+// newPullError classifies an error returned while requesting endpoint.
+func newPullError(endpoint string, err error) ReportError {
+	re := ReportError{
+		Endpoint: endpoint,
+		Phase:    phasePull,
+		Class:    classUnknown,
+		Message:  err.Error(),
+	}
+
+	var (
+		se *statusError
+		ne *networkError
+		ae *authError
+		te net.Error
+	)
+	switch {
+	case errors.As(err, &se):
+		re.Class = classHTTPStatus
+		re.StatusCode = se.code
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &te) && te.Timeout():
+		re.Class = classTimeout
+	case errors.As(err, &ne):
+		re.Class = classNetwork
+	case errors.As(err, &ae):
+		re.Class = classAuth
+	}
+	return re
+}
+
+// newParseError classifies an error that occurred while reading the response
+// from endpoint.
+func newParseError(endpoint string, err error) ReportError {
+	return ReportError{
+		Endpoint: endpoint,
+		Phase:    phaseParse,
+		Class:    classDecode,
+		Message:  err.Error(),
+	}
+}
@@ -0,0 +1,41 @@
+// +build unit
+
+package signal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+)
+
+func TestNewPullError(t *testing.T) {
+	for _, tc := range []struct {
+		err   error
+		class string
+	}{
+		{&statusError{code: 503, status: "503 Service Unavailable"}, classHTTPStatus},
+		{attemptsError(&statusError{code: 503}, 3), classHTTPStatus},
+		{&networkError{&url.Error{Op: "Get", URL: "http://foo", Err: context.DeadlineExceeded}}, classTimeout},
+		{&networkError{errors.New("connection refused")}, classNetwork},
+		{&authError{errors.New("bouncer unavailable")}, classAuth},
+		{errors.New("something else"), classUnknown},
+	} {
+		re := newPullError("http://foo", tc.err)
+		if re.Class != tc.class {
+			t.Errorf("Expected class %s for %v, got %s", tc.class, tc.err, re.Class)
+		}
+		if re.Phase != phasePull || re.Endpoint != "http://foo" || re.Message != tc.err.Error() {
+			t.Error("Expected pull error for endpoint with message, got", re)
+		}
+	}
+
+	if re := newPullError("", attemptsError(&statusError{code: 503}, 1)); re.StatusCode != 503 {
+		t.Error("Expected status code 503, got", re.StatusCode)
+	}
+
+	if s := fmt.Sprint(ReportError{Phase: phaseTrack, Message: "nil report"}); s != "track: nil report" {
+		t.Error("Expected 'track: nil report', got", s)
+	}
+}
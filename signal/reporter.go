@@ -2,104 +2,271 @@ package signal
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/dcos/dcos-signal/config"
 	"gopkg.in/segmentio/analytics-go.v2"
 )
 
-// Reporter expresses a generic DC/OS service report
+// Reporter expresses a generic DC/OS service report. Packages compiled into
+// signal can add their own reporters with RegisterReporter.
 type Reporter interface {
 	// Retrieve the endpoints for the service report
-	getEndpoints() []string
+	GetEndpoints() []string
 	// The HTTP method to execute the report retrival
-	getMethod() string
+	GetMethod() string
 	// Retrieve the headers for the HTTP request
-	getHeaders() map[string]string
+	GetHeaders() map[string]string
 	// Add headers
-	addHeaders(map[string]string)
+	AddHeaders(map[string]string)
 	// Setup the analytics.Track type
-	setReport([]byte) error
+	SetReport([]byte) error
 	// Retreieve the analytics.Track type
-	getReport() interface{}
+	GetReport() interface{}
 	// Create generic track
-	setTrack(config.Config) error
+	SetTrack(config.Config) error
 	// Retrieve only track data
-	getTrack() *analytics.Track
-	// Send track to segmentIO
-	sendTrack(config.Config) error
+	GetTrack() *analytics.Track
 	// Get the name of this Reporter
-	getName() string
-	// Set an error message
-	appendError(string)
-	// Get an error message
-	getError() []string
+	GetName() string
+	// Record an error that occurred while gathering the report
+	AppendError(ReportError)
+	// Get the errors that occurred while gathering the report
+	GetError() []ReportError
+}
+
+// BodyReporter is implemented by reporters that send a request body other than
+// the default "{}".
+type BodyReporter interface {
+	Reporter
+	// Retrieve the body for the HTTP request
+	GetBody() string
+}
+
+// EndpointReporter is implemented by reporters whose endpoints return
+// differently shaped responses, and so need to know which endpoint a response
+// came from. SetEndpointReport is called instead of SetReport.
+type EndpointReporter interface {
+	Reporter
+	// Setup the report from the response of the given endpoint
+	SetEndpointReport(endpoint string, body []byte) error
+}
+
+// Request is a request a reporter makes besides its endpoints.
+type Request struct {
+	// Key identifies the request to the reporter that made it
+	Key      string
+	Endpoint string
+	Method   string
+	Headers  map[string]string
+	Body     string
+}
+
+// FollowUpReporter is implemented by reporters that need requests built from
+// the responses of their endpoints, e.g. one per item of a list. FollowUps is
+// called once all endpoint responses are set; its requests are made like
+// endpoint requests and within the same reporter timeout, and every response is
+// handed to SetFollowUpReport.
+type FollowUpReporter interface {
+	Reporter
+	// Requests to make based on the responses of the endpoints
+	FollowUps() []Request
+	// Setup the report from the response to a follow-up request
+	SetFollowUpReport(req Request, body []byte) error
+}
+
+// EndpointCounter is implemented by reporters that need a fixed number of
+// endpoints, so that a config that gives them any other number is rejected
+// before the run.
+type EndpointCounter interface {
+	Reporter
+	// Number of endpoints the reporter needs
+	EndpointCount() int
+}
+
+// TrackEventer is implemented by reporters that name the event of their track
+// up front, so that a track carrying only their errors can be sent when they
+// gathered nothing to track.
+type TrackEventer interface {
+	Reporter
+	// Event of the track set by SetTrack
+	TrackEvent(c config.Config) string
+}
+
+// errorTrack returns a track carrying only the errors of r, for reporters that
+// set no track. It returns nil if r does not name its event.
+func errorTrack(r Reporter, c config.Config) *analytics.Track {
+	te, ok := r.(TrackEventer)
+	if !ok {
+		return nil
+	}
+	return &analytics.Track{
+		Event:       te.TrackEvent(c),
+		UserId:      c.CustomerKey,
+		AnonymousId: c.ClusterID,
+		Properties: map[string]interface{}{
+			"errors": append([]ReportError{}, r.GetError()...),
+		},
+	}
+}
+
+// endpointRequest returns the request for one of the endpoints of r.
+func endpointRequest(r Reporter, endpoint string) Request {
+	body := "{}"
+	if br, ok := r.(BodyReporter); ok {
+		body = br.GetBody()
+	}
+	return Request{
+		Endpoint: endpoint,
+		Method:   r.GetMethod(),
+		Headers:  r.GetHeaders(),
+		Body:     body,
+	}
+}
+
+// trackProperties adds the properties every track carries to properties and
+// returns it.
+func trackProperties(c config.Config, properties map[string]interface{}) map[string]interface{} {
+	properties["source"] = "cluster"
+	properties["customerKey"] = c.CustomerKey
+	properties["environmentVersion"] = c.DCOSVersion
+	properties["clusterId"] = c.ClusterID
+	properties["licenseId"] = c.LicenseID
+	properties["variant"] = c.DCOSVariant
+	properties["platform"] = c.GenPlatform
+	properties["provider"] = c.GenProvider
+	return properties
 }
 
 // PullReport executes retrival of a service report
-func PullReport(endpoint string, r Reporter, c config.Config) error {
-	url, err := url.Parse(endpoint)
+func PullReport(ctx context.Context, endpoint string, r Reporter, c config.Config) error {
+	body, err := fetchReport(ctx, endpoint, r, c)
 	if err != nil {
 		return err
 	}
 
-	log.Debugf("Pulling from %s", endpoint)
-	client := http.Client{
-		Timeout: time.Duration(15 * time.Second),
+	if err := setReport(r, endpoint, body); err != nil {
+		return err
 	}
 
-	if url.Scheme == "https" {
-		var tlsClientConfig *tls.Config
-		if c.CAPool == nil {
-			// do HTTPS without certificate verification.
-			tlsClientConfig = &tls.Config{
-				InsecureSkipVerify: true,
-			}
-		} else {
-			tlsClientConfig = &tls.Config{
-				RootCAs: c.CAPool,
-			}
-		}
+	return nil
+}
 
-		client.Transport = &http.Transport{
-			TLSClientConfig: tlsClientConfig,
-		}
+// setReport hands the response from endpoint to r.
+func setReport(r Reporter, endpoint string, body []byte) error {
+	if er, ok := r.(EndpointReporter); ok {
+		return er.SetEndpointReport(endpoint, body)
 	}
+	return r.SetReport(body)
+}
 
-	urlStr := fmt.Sprintf("%v", url)
-	method := r.getMethod()
-	reqBody := "{}"
-	req, _ := http.NewRequest(method, urlStr, bytes.NewBufferString(reqBody))
-
-	headers := r.getHeaders()
-	for headerName, headerValue := range headers {
-		req.Header.Add(headerName, headerValue)
+// fetchAttempt makes a single request and returns the response body.
+func fetchAttempt(ctx context.Context, request Request, c config.Config) ([]byte, error) {
+	endpoint := request.Endpoint
+	url, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
 	}
-	log.Debugf("Request %s: %+v", endpoint, req)
-	resp, err := client.Do(req)
+
+	log.Debugf("Pulling from %s", endpoint)
+	client := newHTTPClient(url, c)
+
+	urlStr := fmt.Sprintf("%v", url)
+	resp, err := doRequest(client, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, request.Method, urlStr, bytes.NewBufferString(request.Body))
+		if err != nil {
+			return nil, err
+		}
+		for headerName, headerValue := range request.Headers {
+			req.Header.Add(headerName, headerValue)
+		}
+		log.Debugf("Request %s: %+v", endpoint, req)
+		return req, nil
+	}, c)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("response %s %s: %s", resp.Proto, endpoint, resp.Status)
+		return nil, &statusError{
+			endpoint:   endpoint,
+			proto:      resp.Proto,
+			status:     resp.Status,
+			code:       resp.StatusCode,
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return nil, &networkError{err}
 	}
 	log.Debugf("Response %s: %s, proto %s", resp.Proto, endpoint, resp.Status)
 
-	if err := r.setReport(body); err != nil {
-		return err
+	return body, nil
+}
+
+// doRequest sends the request built by newReq with client. If signal has
+// credentials, the request carries a token from c.Auth, and when that token is
+// rejected doRequest logs in again and sends a new request once more.
+// Transport errors are returned as networkError.
+func doRequest(client *http.Client, newReq func() (*http.Request, error), c config.Config) (*http.Response, error) {
+	for reauthenticated := false; ; reauthenticated = true {
+		req, err := newReq()
+		if err != nil {
+			return nil, err
+		}
+
+		var token string
+		if c.Auth != nil {
+			if token, err = c.Auth.Token(); err != nil {
+				return nil, &authError{err}
+			}
+			req.Header.Set("Authorization", "token="+token)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, &networkError{err}
+		}
+		if resp.StatusCode != http.StatusUnauthorized || c.Auth == nil || reauthenticated {
+			return resp, nil
+		}
+
+		resp.Body.Close()
+		log.Warnf("Token rejected by %s, logging in again", req.URL.Redacted())
+		c.Auth.Invalidate(token)
 	}
+}
 
-	return nil
+// newHTTPClient returns a client for requests to u, trusting c.CAPool for HTTPS.
+func newHTTPClient(u *url.URL, c config.Config) *http.Client {
+	client := &http.Client{
+		Timeout: c.ReporterTimeout,
+	}
+
+	if u.Scheme == "https" {
+		var tlsClientConfig *tls.Config
+		if c.CAPool == nil {
+			// do HTTPS without certificate verification.
+			tlsClientConfig = &tls.Config{
+				InsecureSkipVerify: true,
+			}
+		} else {
+			tlsClientConfig = &tls.Config{
+				RootCAs: c.CAPool,
+			}
+		}
+
+		client.Transport = &http.Transport{
+			TLSClientConfig: tlsClientConfig,
+		}
+	}
+	return client
 }
@@ -3,8 +3,13 @@
 package signal
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/dcos/dcos-signal/config"
 	"gopkg.in/segmentio/analytics-go.v2"
@@ -17,37 +22,35 @@ type testReportType struct {
 	Report    string
 }
 
-func (t *testReportType) getReport() interface{} { return t.Report }
+func (t *testReportType) GetReport() interface{} { return t.Report }
 
-func (t *testReportType) setTrack(config.Config) error { return nil }
+func (t *testReportType) SetTrack(config.Config) error { return nil }
 
-func (t *testReportType) getTrack() (a *analytics.Track) { return a }
+func (t *testReportType) GetTrack() (a *analytics.Track) { return a }
 
-func (t *testReportType) sendTrack(config.Config) error { return nil }
+func (t *testReportType) GetName() string { return "" }
 
-func (t *testReportType) getName() string { return "" }
+func (t *testReportType) AppendError(ReportError) {}
 
-func (t *testReportType) appendError(string) {}
-
-func (t *testReportType) getError() []string { return []string{""} }
+func (t *testReportType) GetError() []ReportError { return nil }
 
 func (t *testReportType) setEndpoints(url []string) { t.Endpoints = url }
 
-func (t *testReportType) getEndpoints() []string { return t.Endpoints }
+func (t *testReportType) GetEndpoints() []string { return t.Endpoints }
 
 func (t *testReportType) setMethod(meth string) { t.Method = meth }
 
-func (t *testReportType) getMethod() string { return t.Method }
+func (t *testReportType) GetMethod() string { return t.Method }
 
-func (t *testReportType) getHeaders() map[string]string { return t.Headers }
+func (t *testReportType) GetHeaders() map[string]string { return t.Headers }
 
-func (t *testReportType) addHeaders(head map[string]string) {
+func (t *testReportType) AddHeaders(head map[string]string) {
 	for k, v := range head {
 		t.Headers[k] = v
 	}
 }
 
-func (t *testReportType) setReport(report []byte) error {
+func (t *testReportType) SetReport(report []byte) error {
 	t.Report = string(report)
 	return nil
 }
@@ -64,9 +67,69 @@ func TestPullHealthReport(t *testing.T) {
 	)
 
 	for _, e := range tr.Endpoints {
-		goodReportErr := PullReport(e, &tr, tc)
+		goodReportErr := PullReport(context.Background(), e, &tr, tc)
 		if goodReportErr != nil {
 			t.Error("Expected nil error, got ", goodReportErr.Error())
 		}
 	}
 }
+
+func TestFetchReauthenticates(t *testing.T) {
+	var (
+		logins   int
+		accepted = "token=second"
+		requests []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Header.Get("Authorization"))
+		if r.Header.Get("Authorization") != accepted {
+			http.Error(w, http.StatusText(401), 401)
+			return
+		}
+		fmt.Fprint(w, "OK")
+	}))
+	defer ts.Close()
+
+	c := config.DefaultConfig()
+	c.Auth = config.NewTokenProvider(func() (string, time.Time, error) {
+		logins++
+		if logins == 1 {
+			return "first", time.Now().Add(time.Hour), nil
+		}
+		return "second", time.Now().Add(time.Hour), nil
+	})
+
+	body, err := fetchAttempt(context.Background(), Request{Endpoint: ts.URL, Method: "GET"}, c)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if string(body) != "OK" {
+		t.Error("Expected OK, got", string(body))
+	}
+	if logins != 2 || len(requests) != 2 || requests[0] != "token=first" {
+		t.Error("Expected one rejected request and one login again, got", logins, requests)
+	}
+
+	// A token that keeps being rejected is not retried more than once.
+	accepted = "token=never"
+	_, err = fetchAttempt(context.Background(), Request{Endpoint: ts.URL, Method: "GET"}, c)
+	var se *statusError
+	if !errors.As(err, &se) || se.code != 401 {
+		t.Error("Expected 401 status error, got", err)
+	}
+	if logins != 3 || len(requests) != 4 {
+		t.Error("Expected a single login again, got", logins, requests)
+	}
+
+	// Failing to log in is an auth error and leaves the endpoint alone.
+	c.Auth = config.NewTokenProvider(func() (string, time.Time, error) {
+		return "", time.Time{}, errors.New("bouncer unavailable")
+	})
+	_, err = fetchAttempt(context.Background(), Request{Endpoint: ts.URL, Method: "GET"}, c)
+	if re := newPullError(ts.URL, err); re.Class != classAuth {
+		t.Error("Expected auth error, got", re)
+	}
+	if len(requests) != 4 {
+		t.Error("Expected no request without a token, got", requests)
+	}
+}
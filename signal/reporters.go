@@ -2,20 +2,23 @@ package signal
 
 import (
 	"github.com/dcos/dcos-signal/config"
+	log "github.com/sirupsen/logrus"
 )
 
-func makeReporters(c config.Config) ([]Reporter, error) {
-
-	var reporters = []Reporter{
-		&Diagnostics{
+func init() {
+	RegisterReporter("diagnostics", func(c config.Config) (Reporter, error) {
+		return &Diagnostics{
 			Name:      "diagnostics",
 			Endpoints: c.DiagnosticsURLs,
 			Method:    "GET",
 			Headers: map[string]string{
 				"content-type": "application/json",
 			},
-		},
-		&Cosmos{
+		}, nil
+	})
+
+	RegisterReporter("cosmos", func(c config.Config) (Reporter, error) {
+		return &Cosmos{
 			Name:      "cosmos",
 			Endpoints: c.CosmosURLs,
 			Method:    "POST",
@@ -23,20 +26,119 @@ func makeReporters(c config.Config) ([]Reporter, error) {
 				"content-type": "application/vnd.dcos.package.list-request+json;charset=utf-8;version=v1",
 				"accept":       "application/vnd.dcos.package.list-response+json;charset=utf-8;version=v1",
 			},
-		},
-		&Mesos{
+		}, nil
+	})
+
+	RegisterReporter("mesos", func(c config.Config) (Reporter, error) {
+		m := &Mesos{
 			Name:      "mesos",
 			Endpoints: c.MesosURLs,
 			Method:    "GET",
 			Headers: map[string]string{
 				"content-type": "application/json",
 			},
-		},
-	}
+		}
+		// Task deltas need to know which master counted the tasks.
+		if c.StateDir != "" {
+			if u, err := mesosMasterURL(c, "/master/state-summary"); err == nil {
+				m.SummaryURL = u.String()
+			}
+		}
+		return m, nil
+	})
+
+	RegisterReporter("marathon", func(c config.Config) (Reporter, error) {
+		// Marathon URLs are optional, older signal configs do not have them.
+		if len(c.MarathonURLs) == 0 {
+			return nil, nil
+		}
+		return &Marathon{
+			Name:      "marathon",
+			Endpoints: c.MarathonURLs,
+			Method:    "GET",
+			Headers: map[string]string{
+				"content-type": "application/json",
+				"accept":       "application/json",
+			},
+		}, nil
+	})
+
+	RegisterReporter("metronome", func(c config.Config) (Reporter, error) {
+		// Metronome URLs are optional, older signal configs do not have them.
+		if len(c.MetronomeURLs) == 0 {
+			return nil, nil
+		}
+		return &Metronome{
+			Name:      "metronome",
+			Endpoints: c.MetronomeURLs,
+			Method:    "GET",
+			Headers: map[string]string{
+				"content-type": "application/json",
+				"accept":       "application/json",
+			},
+		}, nil
+	})
 
-	for _, r := range reporters {
-		r.addHeaders(c.ExtraHeaders)
-	}
+	RegisterReporter("agents", func(c config.Config) (Reporter, error) {
+		// The agents are read from the same master as the mesos report.
+		if len(c.MesosURLs) == 0 {
+			return nil, nil
+		}
+		endpoints, err := mesosMasterEndpoints(c, "/master/slaves")
+		if err != nil {
+			log.Warnf("Skipping agents reporter, invalid mesos_urls: %s", err)
+			return nil, nil
+		}
+		return &Agents{
+			Name:      "agents",
+			Endpoints: endpoints,
+			Method:    "GET",
+			Headers: map[string]string{
+				"content-type": "application/json",
+			},
+		}, nil
+	})
 
-	return reporters, nil
+	RegisterReporter("roles", func(c config.Config) (Reporter, error) {
+		if len(c.MesosURLs) == 0 {
+			return nil, nil
+		}
+		endpoints, err := mesosMasterEndpoints(c, "/master/roles", "/master/quota")
+		if err != nil {
+			log.Warnf("Skipping roles reporter, invalid mesos_urls: %s", err)
+			return nil, nil
+		}
+		return &Roles{
+			Name:      "roles",
+			Endpoints: endpoints,
+			Method:    "GET",
+			Headers: map[string]string{
+				"content-type": "application/json",
+			},
+		}, nil
+	})
+
+	RegisterReporter("maintenance", func(c config.Config) (Reporter, error) {
+		if len(c.MesosURLs) == 0 {
+			return nil, nil
+		}
+		paths := []string{"/master/maintenance/schedule", "/master/maintenance/status", "/metrics/snapshot"}
+		if c.MaintenanceRegistry {
+			paths = append(paths, "/registrar(1)/registry")
+		}
+		endpoints, err := mesosMasterEndpoints(c, paths...)
+		if err != nil {
+			log.Warnf("Skipping maintenance reporter, invalid mesos_urls: %s", err)
+			return nil, nil
+		}
+		return &Maintenance{
+			Name:      "maintenance",
+			Endpoints: endpoints,
+			Registry:  c.MaintenanceRegistry,
+			Method:    "GET",
+			Headers: map[string]string{
+				"content-type": "application/json",
+			},
+		}, nil
+	})
 }
@@ -0,0 +1,176 @@
+package signal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dcos/dcos-signal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// statusError is returned by fetchAttempt for responses other than 200 OK.
+type statusError struct {
+	endpoint   string
+	proto      string
+	status     string
+	code       int
+	retryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("response %s %s: %s", e.proto, e.endpoint, e.status)
+}
+
+// networkError is returned by fetchAttempt when no response was received at all.
+type networkError struct {
+	err error
+}
+
+func (e *networkError) Error() string {
+	return e.err.Error()
+}
+
+func (e *networkError) Unwrap() error {
+	return e.err
+}
+
+// authError is returned by fetchAttempt when no token could be obtained to
+// authenticate the request with.
+type authError struct {
+	err error
+}
+
+func (e *authError) Error() string {
+	return fmt.Sprintf("authentication failed: %s", e.err)
+}
+
+func (e *authError) Unwrap() error {
+	return e.err
+}
+
+// fetchReport requests a single endpoint for the given reporter and returns the
+// response body, retrying according to the reporter's retry policy. It does not
+// modify the reporter, so it is safe to call concurrently for several endpoints
+// of the same reporter. Errors carry the number of attempts that were made.
+func fetchReport(ctx context.Context, endpoint string, r Reporter, c config.Config) ([]byte, error) {
+	return fetchRequest(ctx, r.GetName(), endpointRequest(r, endpoint), c)
+}
+
+// fetchRequest makes a request on behalf of the named reporter, retrying
+// according to its retry policy.
+func fetchRequest(ctx context.Context, name string, req Request, c config.Config) ([]byte, error) {
+	policy := c.RetryPolicyFor(name)
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	endpoint := req.Endpoint
+
+	for attempt := 1; ; attempt++ {
+		body, err := fetchAttempt(ctx, req, c)
+		if err == nil {
+			if attempt > 1 {
+				log.Infof("%s: %s succeeded after %d attempts", name, endpoint, attempt)
+			}
+			return body, nil
+		}
+
+		wait, retry := retryDelay(policy, attempt, err)
+		if !retry || attempt >= maxAttempts {
+			return nil, attemptsError(err, attempt)
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			log.Warnf("%s: not retrying %s, deadline is before next attempt", name, endpoint)
+			return nil, attemptsError(err, attempt)
+		}
+
+		log.Warnf("%s: attempt %d of %d failed, retrying in %s: %s", name, attempt, maxAttempts, wait, err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, attemptsError(err, attempt)
+		case <-timer.C:
+		}
+	}
+}
+
+func attemptsError(err error, attempts int) error {
+	if attempts == 1 {
+		return fmt.Errorf("%w (1 attempt)", err)
+	}
+	return fmt.Errorf("%w (%d attempts)", err, attempts)
+}
+
+// retryDelay returns how long to wait before the next attempt, and whether err
+// is worth another attempt at all.
+func retryDelay(p config.RetryPolicy, attempt int, err error) (time.Duration, bool) {
+	var (
+		se *statusError
+		ne *networkError
+	)
+	switch {
+	case errors.As(err, &se):
+		if !p.ShouldRetryStatus(se.code) {
+			return 0, false
+		}
+		if se.retryAfter > 0 {
+			return se.retryAfter, true
+		}
+	case errors.As(err, &ne):
+		if !p.ShouldRetryNetworkErrors() {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	return backoff(p, attempt), true
+}
+
+// backoff returns the exponential backoff after the given attempt, capped at
+// MaxBackoff and reduced by a random share of up to Jitter.
+func backoff(p config.RetryPolicy, attempt int) time.Duration {
+	if p.BaseBackoff.Duration <= 0 {
+		return 0
+	}
+
+	shift := uint(attempt - 1)
+	if shift > 30 {
+		shift = 30
+	}
+	d := p.BaseBackoff.Duration << shift
+	if max := p.MaxBackoff.Duration; max > 0 && (d > max || d <= 0) {
+		d = max
+	}
+
+	if p.Jitter > 0 {
+		d -= time.Duration(rand.Float64() * p.Jitter * float64(d))
+	}
+	return d
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an
+// HTTP date. It returns 0 if the header is missing or invalid.
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
@@ -0,0 +1,185 @@
+// +build unit
+
+package signal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-signal/config"
+)
+
+// flakyServer fails the first failures requests with status, then serves an
+// empty package list.
+func flakyServer(failures int32, status int, retryAfter string) (*httptest.Server, *int32) {
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= failures {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		fmt.Fprint(w, `{"packages": []}`)
+	}))
+	return s, &calls
+}
+
+func retryConfig() config.Config {
+	c := config.DefaultConfig()
+	c.Retry.BaseBackoff = config.Duration{Duration: time.Millisecond}
+	c.Retry.MaxBackoff = config.Duration{Duration: 5 * time.Millisecond}
+	return c
+}
+
+func TestFetchReportRetriesUnavailable(t *testing.T) {
+	s, calls := flakyServer(2, http.StatusServiceUnavailable, "")
+	defer s.Close()
+
+	r := &Cosmos{Name: "cosmos", Method: "POST"}
+	if _, err := fetchReport(context.Background(), s.URL, r, retryConfig()); err != nil {
+		t.Error("Expected nil error after retries, got", err)
+	}
+
+	if *calls != 3 {
+		t.Error("Expected 3 attempts, got", *calls)
+	}
+}
+
+func TestFetchReportGivesUp(t *testing.T) {
+	s, calls := flakyServer(10, http.StatusServiceUnavailable, "")
+	defer s.Close()
+
+	r := &Cosmos{Name: "cosmos", Method: "POST"}
+	_, err := fetchReport(context.Background(), s.URL, r, retryConfig())
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+
+	if !strings.HasSuffix(err.Error(), "(3 attempts)") {
+		t.Error("Expected attempt count in error, got", err)
+	}
+
+	if *calls != 3 {
+		t.Error("Expected 3 attempts, got", *calls)
+	}
+}
+
+func TestFetchReportDoesNotRetryClientErrors(t *testing.T) {
+	s, calls := flakyServer(10, http.StatusBadRequest, "")
+	defer s.Close()
+
+	r := &Cosmos{Name: "cosmos", Method: "POST"}
+	if _, err := fetchReport(context.Background(), s.URL, r, retryConfig()); err == nil {
+		t.Error("Expected error, got nil")
+	}
+
+	if *calls != 1 {
+		t.Error("Expected 1 attempt, got", *calls)
+	}
+}
+
+func TestFetchReportPerReporterPolicy(t *testing.T) {
+	s, calls := flakyServer(10, http.StatusServiceUnavailable, "")
+	defer s.Close()
+
+	c := retryConfig()
+	c.ReporterRetry = map[string]config.RetryPolicy{
+		"cosmos": {MaxAttempts: 5},
+	}
+
+	r := &Cosmos{Name: "cosmos", Method: "POST"}
+	fetchReport(context.Background(), s.URL, r, c)
+
+	if *calls != 5 {
+		t.Error("Expected 5 attempts, got", *calls)
+	}
+}
+
+func TestFetchReportHonorsRetryAfter(t *testing.T) {
+	s, calls := flakyServer(1, http.StatusTooManyRequests, "1")
+	defer s.Close()
+
+	r := &Cosmos{Name: "cosmos", Method: "POST"}
+	start := time.Now()
+	if _, err := fetchReport(context.Background(), s.URL, r, retryConfig()); err != nil {
+		t.Error("Expected nil error after retry, got", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Error("Expected to wait for Retry-After, waited", elapsed)
+	}
+
+	if *calls != 2 {
+		t.Error("Expected 2 attempts, got", *calls)
+	}
+}
+
+func TestFetchReportStopsAtDeadline(t *testing.T) {
+	s, calls := flakyServer(10, http.StatusTooManyRequests, "60")
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	r := &Cosmos{Name: "cosmos", Method: "POST"}
+	if _, err := fetchReport(ctx, s.URL, r, retryConfig()); err == nil {
+		t.Error("Expected error, got nil")
+	}
+
+	if *calls != 1 {
+		t.Error("Expected 1 attempt, got", *calls)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := config.RetryPolicy{
+		BaseBackoff: config.Duration{Duration: 100 * time.Millisecond},
+		MaxBackoff:  config.Duration{Duration: time.Second},
+	}
+
+	for attempt, expected := range map[int]time.Duration{
+		1:  100 * time.Millisecond,
+		2:  200 * time.Millisecond,
+		4:  800 * time.Millisecond,
+		5:  time.Second,
+		99: time.Second,
+	} {
+		if d := backoff(p, attempt); d != expected {
+			t.Errorf("Expected backoff %s after attempt %d, got %s", expected, attempt, d)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d := backoff(p, 1); d < 50*time.Millisecond || d > 100*time.Millisecond {
+			t.Fatal("Expected jittered backoff between 50ms and 100ms, got", d)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d := parseRetryAfter("3"); d != 3*time.Second {
+		t.Error("Expected 3s, got", d)
+	}
+
+	if d := parseRetryAfter(""); d != 0 {
+		t.Error("Expected 0, got", d)
+	}
+
+	if d := parseRetryAfter("soon"); d != 0 {
+		t.Error("Expected 0, got", d)
+	}
+
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if d := parseRetryAfter(date); d <= 0 || d > time.Minute {
+		t.Error("Expected up to a minute, got", d)
+	}
+}
@@ -0,0 +1,173 @@
+package signal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/dcos/dcos-signal/config"
+	"gopkg.in/segmentio/analytics-go.v2"
+)
+
+// RolesReport is composed of the responses of the Mesos master /master/roles
+// and /master/quota endpoints.
+type RolesReport struct {
+	Roles  []MesosRole  `json:"roles"`
+	Quotas []MesosQuota `json:"quotas"`
+}
+
+// MesosRole defines the fields of a role in the /master/roles response
+type MesosRole struct {
+	Name       string         `json:"name"`
+	Weight     float64        `json:"weight"`
+	Frameworks []string       `json:"frameworks"`
+	Resources  MesosResources `json:"resources"`
+}
+
+// MesosQuota defines the fields of a quota in the /master/quota response
+type MesosQuota struct {
+	Role      string         `json:"role"`
+	Guarantee MesosResources `json:"guarantee"`
+}
+
+// RoleQuota is an entry of the quotas property: the guarantee of a role and
+// the resources its frameworks are allocated.
+type RoleQuota struct {
+	Guarantee MesosResources `json:"guarantee"`
+	Used      MesosResources `json:"used"`
+}
+
+// mesosDefaultRole is the role of unreserved resources, it is always present.
+const mesosDefaultRole = "*"
+
+// Roles implements a Reporter for the roles and quotas of the Mesos master
+type Roles struct {
+	Report    *RolesReport
+	Name      string
+	Endpoints []string
+	Method    string
+	Headers   map[string]string
+	Track     *analytics.Track
+	Error     []ReportError
+}
+
+func (r *Roles) GetName() string {
+	return r.Name
+}
+
+// SetReport is not used, since the endpoint of a response decides how it is
+// read. See SetEndpointReport.
+func (r *Roles) SetReport(body []byte) error {
+	return fmt.Errorf("%s needs to know the endpoint of a report", r.Name)
+}
+
+func (r *Roles) SetEndpointReport(endpoint string, body []byte) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	if r.Report == nil {
+		r.Report = &RolesReport{}
+	}
+
+	switch path := strings.TrimSuffix(u.Path, "/"); {
+	case strings.HasSuffix(path, "/roles"):
+		var roles struct {
+			Roles []MesosRole `json:"roles"`
+		}
+		if err := json.Unmarshal(body, &roles); err != nil {
+			return err
+		}
+		r.Report.Roles = roles.Roles
+	case strings.HasSuffix(path, "/quota"):
+		var quota struct {
+			Infos []MesosQuota `json:"infos"`
+		}
+		if err := json.Unmarshal(body, &quota); err != nil {
+			return err
+		}
+		r.Report.Quotas = quota.Infos
+	default:
+		return fmt.Errorf("unknown roles endpoint %s", endpoint)
+	}
+	return nil
+}
+
+func (r *Roles) GetReport() interface{} {
+	return r.Report
+}
+
+func (r *Roles) AddHeaders(head map[string]string) {
+	for k, v := range head {
+		r.Headers[k] = v
+	}
+}
+
+func (r *Roles) GetHeaders() map[string]string {
+	return r.Headers
+}
+
+// EndpointCount returns the number of endpoints Roles needs.
+func (r *Roles) EndpointCount() int {
+	return 2
+}
+
+func (r *Roles) GetEndpoints() []string {
+	return r.Endpoints
+}
+
+func (r *Roles) GetMethod() string {
+	return r.Method
+}
+
+func (r *Roles) GetError() []ReportError {
+	return r.Error
+}
+
+func (r *Roles) AppendError(err ReportError) {
+	r.Error = append(r.Error, err)
+}
+
+// TrackEvent returns the event of the Roles track.
+func (r *Roles) TrackEvent(config.Config) string {
+	return "roles_track"
+}
+
+func (r *Roles) SetTrack(c config.Config) error {
+	if r.Report == nil {
+		return fmt.Errorf("%s report is nil, bailing out", r.Name)
+	}
+
+	used := make(map[string]MesosResources)
+	roleCount := 0
+	for _, role := range r.Report.Roles {
+		used[role.Name] = role.Resources
+		if role.Name != mesosDefaultRole {
+			roleCount++
+		}
+	}
+
+	quotas := make(map[string]RoleQuota)
+	for _, q := range r.Report.Quotas {
+		quotas[q.Role] = RoleQuota{Guarantee: q.Guarantee, Used: used[q.Role]}
+	}
+
+	properties := trackProperties(c, map[string]interface{}{
+		"role_count":       roleCount,
+		"roles_with_quota": len(quotas),
+		"quotas":           quotas,
+	})
+
+	r.Track = &analytics.Track{
+		Event:       r.TrackEvent(c),
+		UserId:      c.CustomerKey,
+		AnonymousId: c.ClusterID,
+		Properties:  properties,
+	}
+	return nil
+}
+
+func (r *Roles) GetTrack() *analytics.Track {
+	return r.Track
+}
@@ -0,0 +1,76 @@
+// +build unit
+
+package signal
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/dcos/dcos-signal/config"
+)
+
+const (
+	mockMesosRoles = `
+{
+  "roles": [
+    {"name": "*", "weight": 1.0, "frameworks": ["fw-1"], "resources": {"cpus": 3, "mem": 512}},
+    {"name": "dev", "weight": 1.0, "frameworks": ["fw-2"], "resources": {"cpus": 1.5, "mem": 1024, "disk": 10}},
+    {"name": "slave_public", "weight": 1.0, "frameworks": [], "resources": {}}
+  ]
+}`
+	// Guarantees come as a list of resources from older masters and as an
+	// object from newer ones.
+	mockMesosQuota = `
+{
+  "infos": [
+    {"role": "dev", "guarantee": [
+      {"name": "cpus", "type": "SCALAR", "scalar": {"value": 4}},
+      {"name": "mem", "type": "SCALAR", "scalar": {"value": 4096}}]},
+    {"role": "ops", "guarantee": {"cpus": 1, "mem": 256}}
+  ]
+}`
+)
+
+func TestRolesTrack(t *testing.T) {
+	c := config.DefaultConfig()
+	c.MesosURLs = []string{fmt.Sprintf("%s/frameworks", server.URL), fmt.Sprintf("%s/metrics/snapshot", server.URL)}
+
+	roles := findReporter(t, c, "roles")
+	if roles == nil {
+		t.Fatal("Expected roles reporter when mesos_urls are set")
+	}
+
+	if err := runner(context.Background(), []Reporter{roles}, c); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if errs := roles.GetError(); len(errs) != 0 {
+		t.Fatal("Expected no errors, got", errs)
+	}
+
+	track := roles.GetTrack()
+	if track.Event != "roles_track" {
+		t.Error("Expected event roles_track, got", track.Event)
+	}
+	if track.Properties["role_count"] != 2 {
+		t.Error("Expected 2 roles besides *, got", track.Properties["role_count"])
+	}
+	if track.Properties["roles_with_quota"] != 2 {
+		t.Error("Expected 2 roles with quota, got", track.Properties["roles_with_quota"])
+	}
+
+	quotas := track.Properties["quotas"].(map[string]RoleQuota)
+	if dev := quotas["dev"]; dev.Guarantee != (MesosResources{CPUs: 4, Mem: 4096}) || dev.Used != (MesosResources{CPUs: 1.5, Mem: 1024, Disk: 10}) {
+		t.Error("Expected dev quota guarantee and usage, got", dev)
+	}
+	if ops := quotas["ops"]; ops.Guarantee != (MesosResources{CPUs: 1, Mem: 256}) || ops.Used != (MesosResources{}) {
+		t.Error("Expected ops quota guarantee without usage, got", ops)
+	}
+}
+
+func TestRolesUnknownEndpoint(t *testing.T) {
+	r := &Roles{Name: "roles"}
+	if err := r.SetEndpointReport("http://localhost/master/weights", []byte("[]")); err == nil {
+		t.Error("Expected error for unknown endpoint, got nil")
+	}
+}
@@ -27,3 +27,39 @@ func CreateSegmentClient(segmentKey string, verbose bool) *analytics.Client {
 	client.Verbose = verbose
 	return client
 }
+
+// CreateUnitTitleKey creates the key for segmentIO properties for the title of a unit. This
+// key has the format: health-unit-$UNIT_ID-title
+func CreateUnitTitleKey(name string) string {
+	return "health-unit-" + strings.Replace(name, ".", "-", -1) + "-title"
+}
+
+// CreateUnitRoleTotalKey creates the key for segmentIO properties for total hosts of a role.
+// This key has the format: health-unit-$UNIT_ID-$ROLE-total
+func CreateUnitRoleTotalKey(name, role string) string {
+	return "health-unit-" + strings.Replace(name, ".", "-", -1) + "-" + role + "-total"
+}
+
+// CreateUnitRoleUnhealthyKey creates the key for segmentIO properties for unhealthy hosts of
+// a role. This key has the format: health-unit-$UNIT_ID-$ROLE-unhealthy
+func CreateUnitRoleUnhealthyKey(name, role string) string {
+	return "health-unit-" + strings.Replace(name, ".", "-", -1) + "-" + role + "-unhealthy"
+}
+
+// CreateNodeRoleTotalKey creates the key for segmentIO properties for total nodes of a role.
+// This key has the format: health-nodes-$ROLE-total
+func CreateNodeRoleTotalKey(role string) string {
+	return "health-nodes-" + role + "-total"
+}
+
+// CreateNodeRoleUnhealthyKey creates the key for segmentIO properties for unhealthy nodes of
+// a role. This key has the format: health-nodes-$ROLE-unhealthy
+func CreateNodeRoleUnhealthyKey(role string) string {
+	return "health-nodes-" + role + "-unhealthy"
+}
+
+// CreateUnitDetailKey creates the key for segmentIO properties for the nodes a unit is unhealthy
+// on. This key has the format: health-unit-$UNIT_ID-unhealthy-detail
+func CreateUnitDetailKey(name string) string {
+	return "health-unit-" + strings.Replace(name, ".", "-", -1) + "-unhealthy-detail"
+}
@@ -33,3 +33,35 @@ func TestCreateSegmentClient(t *testing.T) {
 		t.Error("Expected false, got ", tc.Verbose)
 	}
 }
+
+func TestCreateUnitTitleKey(t *testing.T) {
+	testKey := CreateUnitTitleKey("foo.service")
+	if testKey != "health-unit-foo-service-title" {
+		t.Error("Expected \"health-unit-foo-service-title\", got ", testKey)
+	}
+}
+
+func TestCreateUnitRoleKeys(t *testing.T) {
+	if testKey := CreateUnitRoleTotalKey("foo", "master"); testKey != "health-unit-foo-master-total" {
+		t.Error("Expected \"health-unit-foo-master-total\", got ", testKey)
+	}
+	if testKey := CreateUnitRoleUnhealthyKey("foo", "public_agent"); testKey != "health-unit-foo-public_agent-unhealthy" {
+		t.Error("Expected \"health-unit-foo-public_agent-unhealthy\", got ", testKey)
+	}
+}
+
+func TestCreateNodeRoleKeys(t *testing.T) {
+	if testKey := CreateNodeRoleTotalKey("agent"); testKey != "health-nodes-agent-total" {
+		t.Error("Expected \"health-nodes-agent-total\", got ", testKey)
+	}
+	if testKey := CreateNodeRoleUnhealthyKey("agent"); testKey != "health-nodes-agent-unhealthy" {
+		t.Error("Expected \"health-nodes-agent-unhealthy\", got ", testKey)
+	}
+}
+
+func TestCreateUnitDetailKey(t *testing.T) {
+	testKey := CreateUnitDetailKey("foo.service")
+	if testKey != "health-unit-foo-service-unhealthy-detail" {
+		t.Error("Expected \"health-unit-foo-service-unhealthy-detail\", got ", testKey)
+	}
+}
@@ -1,13 +1,15 @@
 package signal
 
 import (
-	"encoding/json"
-	"errors"
+	"context"
 	"fmt"
 	"os"
+	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/dcos/dcos-signal/config"
+	"github.com/google/uuid"
 	"gopkg.in/segmentio/analytics-go.v2"
 )
 
@@ -16,81 +18,258 @@ var (
 	REVISION = "UNSET"
 )
 
-func runner(reporters []Reporter, c config.Config) error {
+// maxReporterRequests bounds the requests a single reporter makes at a time, so
+// that reporters with a request per item, like cosmos with one per installed
+// package, don't flood the service they ask.
+const maxReporterRequests = 4
+
+// pullResult holds the outcome of fetching a single reporter endpoint.
+type pullResult struct {
+	body []byte
+	err  error
+}
+
+// withTimeout bounds ctx by d, or only makes it cancellable when d is not set.
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// runner gathers the reports of all reporters concurrently. The whole run is
+// bounded by c.RunTimeout and each reporter by c.ReporterTimeout. A reporter
+// pulls all its endpoints concurrently and is handed the results in endpoint
+// order, so reports that are assembled from several endpoints merge the same
+// way on every run. Reporters that need further requests based on those
+// results make them next, within the same timeout, see FollowUpReporter. Once
+// all reporters are done, each builds its track from whatever it received,
+// with the errors of the run in its "errors" property.
+func runner(ctx context.Context, reporters []Reporter, c config.Config) error {
 	for _, r := range reporters {
-		if len(r.getEndpoints()) == 0 {
-			return fmt.Errorf("reporter %s has no endpoints", r.getName())
-		}
-		for _, endpoint := range r.getEndpoints() {
-			log.Debugf("Processing %s endpoint %s", r.getName(), endpoint)
-			if err := PullReport(endpoint, r, c); err != nil {
-				log.Errorf("error setting track for %s: %s", r.getName(), err.Error())
-				r.appendError(err.Error())
-			}
+		if len(r.GetEndpoints()) == 0 {
+			return fmt.Errorf("reporter %s has no endpoints", r.GetName())
+		}
+	}
+
+	ctx, cancel := withTimeout(ctx, c.RunTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, r := range reporters {
+		// Reporters makeReporters found misconfigured only send their errors.
+		if hasConfigError(r) {
+			continue
+		}
+		reporterCtx, cancel := withTimeout(ctx, c.ReporterTimeout)
+		defer cancel()
+
+		wg.Add(1)
+		go func(r Reporter) {
+			defer wg.Done()
+			gather(reporterCtx, r, c)
+		}(r)
+	}
+	wg.Wait()
+
+	for _, r := range reporters {
+		if err := r.SetTrack(c); err != nil {
+			log.Errorf("error setting track for %s: %s", r.GetName(), err.Error())
+			r.AppendError(ReportError{
+				Phase:   phaseTrack,
+				Class:   classIncomplete,
+				Message: err.Error(),
+			})
+		}
 
-			if err := r.setTrack(c); err != nil {
-				log.Errorf("error setting track for %s: %s", r.getName(), err.Error())
-				r.appendError(err.Error())
+		// Whatever was collected is sent along with what went wrong, so an
+		// empty value can be told apart from one that could not be gathered.
+		if track := r.GetTrack(); track != nil {
+			if track.Properties == nil {
+				track.Properties = make(map[string]interface{})
 			}
+			track.Properties["errors"] = append([]ReportError{}, r.GetError()...)
 		}
 	}
 	return nil
 }
 
-func executeTester(data map[string]*analytics.Track, c config.Config) error {
-	jsonStr, err := json.MarshalIndent(data, "", "    ")
-	fmt.Print(string(jsonStr))
-	return err
+// gather pulls the endpoints of r and then its follow-up requests, if any,
+// and hands the results to r.
+func gather(ctx context.Context, r Reporter, c config.Config) {
+	var requests []Request
+	for _, endpoint := range r.GetEndpoints() {
+		requests = append(requests, endpointRequest(r, endpoint))
+	}
+	for i, result := range pullAll(ctx, r.GetName(), requests, c) {
+		endpoint := requests[i].Endpoint
+		if result.err != nil {
+			log.Errorf("error pulling report for %s: %s", r.GetName(), result.err.Error())
+			r.AppendError(newPullError(endpoint, result.err))
+		} else if err := setReport(r, endpoint, result.body); err != nil {
+			log.Errorf("error setting report for %s: %s", r.GetName(), err.Error())
+			r.AppendError(newParseError(endpoint, err))
+		}
+	}
+
+	fr, ok := r.(FollowUpReporter)
+	if !ok {
+		return
+	}
+	followUps := fr.FollowUps()
+	for i, result := range pullAll(ctx, r.GetName(), followUps, c) {
+		req := followUps[i]
+		if result.err != nil {
+			log.Errorf("error pulling follow-up for %s: %s", r.GetName(), result.err.Error())
+			r.AppendError(newPullError(req.Endpoint, result.err))
+		} else if err := fr.SetFollowUpReport(req, result.body); err != nil {
+			log.Errorf("error setting follow-up report for %s: %s", r.GetName(), err.Error())
+			r.AppendError(newParseError(req.Endpoint, err))
+		}
+	}
+}
+
+// pullAll makes requests concurrently on behalf of the named reporter, at most
+// maxReporterRequests at a time, and returns their results in request order.
+func pullAll(ctx context.Context, name string, requests []Request, c config.Config) []pullResult {
+	var (
+		wg      sync.WaitGroup
+		results = make([]pullResult, len(requests))
+		sem     = make(chan struct{}, maxReporterRequests)
+	)
+	for i, req := range requests {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, req Request) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			log.Debugf("Processing %s endpoint %s", name, req.Endpoint)
+			body, err := fetchRequest(ctx, name, req, c)
+			results[i] = pullResult{body: body, err: err}
+		}(i, req)
+	}
+	wg.Wait()
+	return results
 }
 
-func executeRunner(c config.Config) error {
+func executeRunner(ctx context.Context, c config.Config) error {
 	log.Info("==> STARTING SIGNAL RUNNER")
 
 	// Get our channel of jobs (reporters)
 	reporters, err := makeReporters(c)
 	if err != nil {
-		return errors.New("unable to get reporters")
+		return fmt.Errorf("unable to get reporters: %s", err)
 	}
 
-	err = runner(reporters, c)
+	return executeReporters(ctx, reporters, c)
+}
+
+// executeReporters gathers data for the given reporters and sends their tracks.
+func executeReporters(ctx context.Context, reporters []Reporter, c config.Config) error {
+	// Every master runs signal, but only the leader should report. If leadership
+	// cannot be determined we would rather send duplicates than nothing.
+	if c.LeaderOnly && !c.FlagTest {
+		leaderCtx, cancel := withTimeout(ctx, c.ReporterTimeout)
+		leader, err := isLeadingMaster(leaderCtx, c)
+		cancel()
+		if err != nil {
+			log.Warnf("Unable to determine Mesos leadership, reporting anyway: %s", err)
+		} else if !leader {
+			log.Info("Not the leading Mesos master, skipping run")
+			return nil
+		}
+	}
+
+	err := runner(ctx, reporters, c)
 	if err != nil {
 		return fmt.Errorf("error gathering data: %s", err)
 	}
 
-	tester := make(map[string]*analytics.Track)
+	sinks, err := makeSinks(c)
+	if err != nil {
+		return fmt.Errorf("unable to get sinks: %s", err)
+	}
+
+	// Test runs print to stdout only and must not touch the outbox.
+	var ob *outbox
+	if !c.FlagTest {
+		if ob, err = newOutbox(c); err != nil {
+			log.Errorf("error opening outbox, undelivered tracks will be lost: %s", err)
+		}
+	}
+	if ob != nil {
+		ob.redeliver(sinks)
+	}
 
 	processed := 1
 	for _, r := range reporters {
-		for _, err := range r.getError() {
-			log.Errorf("%s: %s", r.getName(), err)
-		}
-		if c.FlagTest {
-			log.Debugf("Adding test data for %s: %+v", r.getName(), r.getTrack())
-			tester[r.getName()] = r.getTrack()
-		} else if len(r.getError()) > 0 {
-			for _, err := range r.getError() {
-				log.Errorf("%s: %s", r.getName(), err)
-			}
+		for _, err := range r.GetError() {
+			log.Errorf("%s: %s", r.GetName(), err)
+		}
+		// Reporters that gathered nothing still send what went wrong, and test
+		// runs print it.
+		track := r.GetTrack()
+		if track == nil {
+			track = errorTrack(r, c)
+		}
+		if track == nil {
+			log.Errorf("%s gathered no data, nothing to send", r.GetName())
 		} else {
-			if err := r.sendTrack(c); err != nil {
-				log.Errorf("error tracking %s: %s", r.getName(), err)
+			stampTrack(track)
+			for _, s := range sinks {
+				if err := s.Send(r.GetName(), track); err != nil {
+					log.Errorf("error tracking %s: %s", r.GetName(), err)
+					if ob != nil {
+						if err := ob.put(s.Name(), r.GetName(), track); err != nil {
+							log.Errorf("error spooling %s track to outbox: %s", r.GetName(), err)
+						}
+					}
+				}
 			}
 		}
 		log.Warnf("processed %d", processed)
 		processed++
 	}
 
-	if c.FlagTest {
-		if err := executeTester(tester, c); err != nil {
-			return err
+	for _, err := range closeSinks(sinks) {
+		log.Errorf("error closing sink: %s", err)
+	}
+
+	if ob != nil {
+		if err := ob.prune(); err != nil {
+			log.Errorf("error pruning outbox: %s", err)
 		}
 	}
 
 	return nil
 }
 
+// stampTrack gives a track its message ID and timestamp before it is first
+// sent. Both are kept when the track is redelivered from the outbox, so that
+// downstream can deduplicate it and still see when it was gathered.
+func stampTrack(track *analytics.Track) {
+	track.Type = "track"
+	if track.MessageId == "" {
+		track.MessageId = uuid.New().String()
+	}
+	if track.Timestamp == "" {
+		track.Timestamp = time.Now().Format(segmentTimeFormat)
+	}
+}
+
 // Start starts the signal service
 func Start() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "validate":
+			os.Exit(executeValidate(os.Args[2:], os.Stdout))
+		case "print-config":
+			os.Exit(executePrintConfig(os.Args[2:], os.Stdout))
+		}
+	}
+
 	config, configErr := config.ParseArgsReturnConfig(os.Args[1:])
 	if configErr != nil {
 		for _, err := range configErr {
@@ -113,7 +292,14 @@ func Start() {
 			log.SetLevel(log.ErrorLevel)
 		}
 	}
-	if err := executeRunner(config); err != nil {
+	if config.FlagDaemon {
+		if err := executeDaemon(config); err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if err := executeRunner(context.Background(), config); err != nil {
 		log.Error(err)
 		os.Exit(1)
 	}
@@ -3,15 +3,17 @@
 package signal
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/dcos/dcos-signal/config"
 	"github.com/gorilla/mux"
-	"gopkg.in/segmentio/analytics-go.v2"
 )
 
 var mockNodes = []*Node{
@@ -64,24 +66,42 @@ var (
 		AppID: "fooPackage",
 	}
 
-	mesosFrameworks = map[string][]string{
-		"frameworks": []string{
-			"fooFramework1",
-			"fooFramework2",
-		},
-	}
+	mesosFrameworks = `
+{
+  "frameworks": [
+    {"id": "fw-1", "name": "fooFramework1", "role": "slave_public", "registered_time": 1547460000.0,
+     "used_resources": {"cpus": 1.5, "mem": 256, "disk": 100, "gpus": 1},
+     "offered_resources": {"cpus": 0.5},
+     "allocated_resources": [
+       {"name": "cpus", "type": "SCALAR", "scalar": {"value": 1.0}, "role": "slave_public"},
+       {"name": "cpus", "type": "SCALAR", "scalar": {"value": 1.0}, "role": "*"},
+       {"name": "disk", "type": "SCALAR", "scalar": {"value": 100.0},
+        "reservations": [{"type": "DYNAMIC", "role": "slave_public"}]},
+       {"name": "ports", "type": "RANGES", "ranges": {"range": [{"begin": 1, "end": 2}]}, "role": "slave_public"}
+     ],
+     "tasks": [{"id": "t1", "state": "TASK_RUNNING"}, {"id": "t2", "state": "TASK_STAGING"}]},
+    {"id": "fw-2", "name": "fooFramework2", "roles": ["a", "b"]}
+  ]
+}`
 
 	mesosMetricsSnapshot = map[string]int{
-		"master/cpus_total":        10,
-		"master/cpus_used":         2,
-		"master/disk_total":        1000,
-		"master/disk_used":         20,
-		"master/mem_total":         2000,
-		"master/mem_used":          200,
-		"master/tasks_running":     4,
-		"master/frameworks_active": 2,
-		"master/slaves_connected":  3,
-		"master/slaves_active":     1,
+		"master/cpus_total":          10,
+		"master/cpus_used":           2,
+		"master/disk_total":          1000,
+		"master/disk_used":           20,
+		"master/mem_total":           2000,
+		"master/mem_used":            200,
+		"master/tasks_running":       4,
+		"master/tasks_staging":       1,
+		"master/tasks_failed":        7,
+		"master/tasks_killed":        3,
+		"master/uptime_secs":         3600,
+		"master/frameworks_active":   2,
+		"master/slaves_connected":    3,
+		"master/slaves_active":       1,
+		"master/slaves_unreachable":  2,
+		"master/slaves_disconnected": 1,
+		"master/slave_removals":      1,
 	}
 
 	mockCosmosReport = &CosmosReport{
@@ -97,7 +117,7 @@ func mockHealthReportHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func mockFrameworksHandler(w http.ResponseWriter, r *http.Request) {
-	json.NewEncoder(w).Encode(mesosFrameworks)
+	fmt.Fprint(w, mesosFrameworks)
 }
 
 func mockMesosStatsHandler(w http.ResponseWriter, r *http.Request) {
@@ -120,6 +140,14 @@ func mockFour(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, http.StatusText(400), 400)
 }
 
+func mockSlow(w http.ResponseWriter, r *http.Request) {
+	select {
+	case <-r.Context().Done():
+	case <-time.After(5 * time.Second):
+	}
+	json.NewEncoder(w).Encode("too late")
+}
+
 func mockTester(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode("OK")
 }
@@ -131,36 +159,253 @@ func mockRouter() *mux.Router {
 		frameworks = "/frameworks"
 		mesosStats = "/metrics/snapshot"
 		tester     = "/tester"
+		slow       = "/slow"
 	)
 	router := mux.NewRouter().StrictSlash(true)
 	router.HandleFunc(health, mockHealthReportHandler).Methods("GET")
 	router.HandleFunc(frameworks, mockFrameworksHandler).Methods("GET")
 	router.HandleFunc(mesosStats, mockMesosStatsHandler).Methods("GET")
 	router.HandleFunc(cosmos, mockCosmosReportHandler).Methods("POST")
+	router.HandleFunc("/package/repository/list", mockJSON(mockCosmosRepositories)).Methods("POST")
+	router.HandleFunc("/package/list-versions", mockCosmosVersionsHandler).Methods("POST")
+	router.HandleFunc("/outdated/package/list", mockJSON(mockCosmosInstalled)).Methods("POST")
+	router.HandleFunc("/outdated/package/repository/list", mockJSON(mockCosmosRepositories)).Methods("POST")
+	router.HandleFunc("/outdated/package/list-versions", mockCosmosVersionsHandler).Methods("POST")
 	router.HandleFunc(fmt.Sprintf("%s/badjson", health), mockBadJson).Methods("GET")
 	router.HandleFunc(fmt.Sprintf("%s/500", health), mockFive).Methods("GET")
 	router.HandleFunc(fmt.Sprintf("%s/400", health), mockFour).Methods("GET")
 	router.HandleFunc(tester, mockTester).Methods("POST")
+	router.HandleFunc(slow, mockSlow)
+	router.HandleFunc("/marathon/v2/apps", mockJSON(mockMarathonApps)).Methods("GET")
+	router.HandleFunc("/marathon/v2/pods", mockJSON(mockMarathonPods)).Methods("GET")
+	router.HandleFunc("/marathon/v2/deployments", mockJSON(mockMarathonDeployments)).Methods("GET")
+	router.HandleFunc("/marathon/v2/info", mockJSON(mockMarathonInfo)).Methods("GET")
+	router.HandleFunc("/metronome/v1/jobs", mockJSON(mockMetronomeJobs)).Methods("GET")
+	router.HandleFunc("/master/state-summary", mockJSON(mockMasterSummary)).Methods("GET")
+	router.HandleFunc("/master/slaves", mockJSON(mockMesosAgents)).Methods("GET")
+	router.HandleFunc("/master/roles", mockJSON(mockMesosRoles)).Methods("GET")
+	router.HandleFunc("/master/quota", mockJSON(mockMesosQuota)).Methods("GET")
+	router.HandleFunc("/master/maintenance/schedule", mockJSON(mockMaintenanceSchedule)).Methods("GET")
+	router.HandleFunc("/master/maintenance/status", mockJSON(mockMaintenanceStatus)).Methods("GET")
+	router.HandleFunc("/registrar(1)/registry", mockJSON(mockRegistry)).Methods("GET")
 	return router
 }
 
-func TestExecuteTester(t *testing.T) {
-	test := true
+// findReporter returns the reporter called name among the ones makeReporters
+// builds from c, or nil if there is none.
+func findReporter(t *testing.T, c config.Config, name string) Reporter {
+	reporters, err := makeReporters(c)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	for _, r := range reporters {
+		if r.GetName() == name {
+			return r
+		}
+	}
+	return nil
+}
 
+func TestRunnerReporterTimeout(t *testing.T) {
 	var (
-		mockTrack = &analytics.Track{}
-		data      = map[string]*analytics.Track{
-			"foo": mockTrack,
+		slowDiag = &Diagnostics{
+			Name:      "diagnostics",
+			Endpoints: []string{fmt.Sprintf("%s/slow", server.URL)},
+			Method:    "GET",
 		}
-		mockConfig = config.Config{
-			FlagTest: test,
+		cosmos = &Cosmos{
+			Name:      "cosmos",
+			Endpoints: []string{fmt.Sprintf("%s/package/list", server.URL)},
+			Method:    "POST",
 		}
+		c = config.DefaultConfig()
 	)
+	c.ReporterTimeout = 50 * time.Millisecond
 
-	err := executeTester(data, mockConfig)
+	start := time.Now()
+	if err := runner(context.Background(), []Reporter{slowDiag, cosmos}, c); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
 
-	if err != nil {
-		t.Error("Expected nil error, got", err)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Error("Expected runner to give up on slow reporter, took", elapsed)
+	}
+
+	if errs := slowDiag.GetError(); len(errs) == 0 || errs[0].Class != classTimeout {
+		t.Error("Expected timeout error for slow reporter, got", errs)
+	}
+
+	if slowDiag.GetTrack() != nil {
+		t.Error("Expected no track for reporter that gathered nothing, got", slowDiag.GetTrack())
+	}
+
+	if len(cosmos.GetError()) != 0 {
+		t.Error("Expected no errors for cosmos, got", cosmos.GetError())
+	}
+
+	if cosmos.GetTrack() == nil {
+		t.Error("Expected cosmos track to be set")
 	}
+}
+
+func TestRunnerMergesEndpoints(t *testing.T) {
+	var (
+		mesos = &Mesos{
+			Name: "mesos",
+			Endpoints: []string{
+				fmt.Sprintf("%s/frameworks", server.URL),
+				fmt.Sprintf("%s/metrics/snapshot", server.URL),
+			},
+			Method: "GET",
+		}
+		c = config.DefaultConfig()
+	)
+
+	if err := runner(context.Background(), []Reporter{mesos}, c); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	if len(mesos.GetError()) != 0 {
+		t.Error("Expected no errors, got", mesos.GetError())
+	}
+
+	if len(mesos.Report.Frameworks) != 2 {
+		t.Error("Expected 2 frameworks from frameworks endpoint, got", len(mesos.Report.Frameworks))
+	}
+
+	if mesos.Report.TaskCount != 4 {
+		t.Error("Expected 4 tasks from metrics endpoint, got", mesos.Report.TaskCount)
+	}
+
+	if mesos.GetTrack() == nil {
+		t.Error("Expected mesos track to be set")
+	}
+}
+
+func TestPullAllBoundsConcurrency(t *testing.T) {
+	var (
+		mu                sync.Mutex
+		inFlight, maxSeen int
+	)
+	bounded := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxSeen {
+			maxSeen = inFlight
+		}
+		mu.Unlock()
 
+		time.Sleep(10 * time.Millisecond)
+		fmt.Fprint(w, "{}")
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	defer bounded.Close()
+
+	requests := make([]Request, 3*maxReporterRequests)
+	for i := range requests {
+		requests[i] = Request{Endpoint: bounded.URL, Method: "GET"}
+	}
+	for i, result := range pullAll(context.Background(), "test", requests, config.DefaultConfig()) {
+		if result.err != nil {
+			t.Errorf("Expected nil error for request %d, got %s", i, result.err)
+		}
+	}
+	if maxSeen > maxReporterRequests {
+		t.Errorf("Expected at most %d requests at a time, got %d", maxReporterRequests, maxSeen)
+	}
+}
+
+func TestRunnerNoEndpoints(t *testing.T) {
+	empty := &Cosmos{Name: "cosmos"}
+	if err := runner(context.Background(), []Reporter{empty}, config.DefaultConfig()); err == nil {
+		t.Error("Expected error for reporter without endpoints, got nil")
+	}
+}
+
+func TestRunnerPartialReport(t *testing.T) {
+	var (
+		failing = fmt.Sprintf("%s/system/health/v1/report/500", server.URL)
+		mesos   = &Mesos{
+			Name: "mesos",
+			Endpoints: []string{
+				failing,
+				fmt.Sprintf("%s/metrics/snapshot", server.URL),
+			},
+			Method: "GET",
+		}
+		c = config.DefaultConfig()
+	)
+
+	if err := runner(context.Background(), []Reporter{mesos}, c); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	track := mesos.GetTrack()
+	if track == nil {
+		t.Fatal("Expected track with partial data, got nil")
+	}
+
+	if track.Properties["task_count"] != float64(4) {
+		t.Error("Expected task_count from the working endpoint, got", track.Properties["task_count"])
+	}
+
+	errs, ok := track.Properties["errors"].([]ReportError)
+	if !ok || len(errs) != 1 {
+		t.Fatal("Expected 1 error in track properties, got", track.Properties["errors"])
+	}
+
+	if errs[0].Endpoint != failing || errs[0].Phase != phasePull || errs[0].Class != classHTTPStatus || errs[0].StatusCode != 500 {
+		t.Error("Expected pull error with status 500 for failing endpoint, got", errs[0])
+	}
+}
+
+func TestRunnerEmptyErrors(t *testing.T) {
+	cosmos := &Cosmos{
+		Name:      "cosmos",
+		Endpoints: []string{fmt.Sprintf("%s/package/list", server.URL)},
+		Method:    "POST",
+	}
+
+	if err := runner(context.Background(), []Reporter{cosmos}, config.DefaultConfig()); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	if errs, ok := cosmos.GetTrack().Properties["errors"].([]ReportError); !ok || len(errs) != 0 {
+		t.Error("Expected empty errors property, got", cosmos.GetTrack().Properties["errors"])
+	}
+}
+
+func TestExecuteReportersErrorTrack(t *testing.T) {
+	var received []map[string]interface{}
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var track map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&track)
+		received = append(received, track)
+	}))
+	defer hook.Close()
+
+	c := config.DefaultConfig()
+	c.ClusterID = "anon"
+	c.Sinks = []config.SinkConfig{{Type: "webhook", URL: hook.URL}}
+	cosmos := &Cosmos{
+		Name:      "cosmos",
+		Endpoints: []string{fmt.Sprintf("%s/system/health/v1/report/500", server.URL)},
+		Method:    "POST",
+	}
+
+	executeReporters(context.Background(), []Reporter{cosmos}, c)
+	if len(received) != 1 {
+		t.Fatal("Expected a track for the reporter that gathered nothing, got", len(received))
+	}
+	properties, _ := received[0]["properties"].(map[string]interface{})
+	if received[0]["event"] != "package_list" || received[0]["anonymousId"] != "anon" || len(properties) != 1 {
+		t.Error("Expected package_list track with only the errors property, got", received[0])
+	}
+	// The failed pull, and the track that could not be set from it
+	if errs, _ := properties["errors"].([]interface{}); len(errs) != 2 {
+		t.Error("Expected 2 errors in the track, got", properties["errors"])
+	}
 }
@@ -0,0 +1,263 @@
+package signal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/dcos/dcos-signal/config"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/segmentio/analytics-go.v2"
+)
+
+const (
+	// Timeout for a single delivery to segmentIO
+	segmentTimeout = 30 * time.Second
+	// Timestamp layout used by segmentIO messages
+	segmentTimeFormat = "2006-01-02T15:04:05-0700"
+)
+
+// Sink delivers the tracks gathered during a run to a single destination.
+type Sink interface {
+	// Name identifies the sink across runs, e.g. for redelivery from the outbox
+	Name() string
+	// Send delivers the track built by the named reporter
+	Send(reporter string, track *analytics.Track) error
+	// Close flushes anything the sink buffered during the run
+	Close() error
+}
+
+// makeSinks returns the sinks configured in c. The -test flag replaces all of
+// them with stdout, and without any configured sinks tracks go to segment.
+func makeSinks(c config.Config) ([]Sink, error) {
+	if c.FlagTest {
+		return []Sink{newStdoutSink(os.Stdout)}, nil
+	}
+
+	sinkConfigs := c.Sinks
+	if len(sinkConfigs) == 0 {
+		sinkConfigs = []config.SinkConfig{{Type: "segment"}}
+	}
+
+	var sinks []Sink
+	for _, sc := range sinkConfigs {
+		s, err := makeSink(sc, c)
+		if err != nil {
+			closeSinks(sinks)
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
+
+func makeSink(sc config.SinkConfig, c config.Config) (Sink, error) {
+	switch sc.Type {
+	case "segment":
+		return &segmentSink{
+			endpoint: analytics.Endpoint,
+			key:      c.SegmentKey,
+			client:   &http.Client{Timeout: segmentTimeout},
+		}, nil
+	case "stdout":
+		return newStdoutSink(os.Stdout), nil
+	case "file":
+		if sc.Path == "" {
+			return nil, errors.New("file sink needs a path")
+		}
+		return &fileSink{path: sc.Path}, nil
+	case "webhook":
+		u, err := url.Parse(sc.URL)
+		if err != nil {
+			return nil, fmt.Errorf("webhook sink has invalid url: %s", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("webhook sink needs an http or https url, got '%s'", sc.URL)
+		}
+		return &webhookSink{
+			url:     sc.URL,
+			headers: sc.Headers,
+			client:  newHTTPClient(u, c),
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown sink type '%s'", sc.Type)
+}
+
+func closeSinks(sinks []Sink) []error {
+	var errs []error
+	for _, s := range sinks {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// segmentSink sends every track to the segmentIO batch API right away. Unlike
+// the analytics client, which only logs failed deliveries in the background and
+// stamps every track with a new message ID, it returns delivery failures so the
+// track can be spooled to the outbox, and sends the track as it was stamped so
+// redeliveries can be deduplicated.
+type segmentSink struct {
+	endpoint string
+	key      string
+	client   *http.Client
+}
+
+func (s *segmentSink) Name() string {
+	return "segment"
+}
+
+func (s *segmentSink) Send(reporter string, track *analytics.Track) error {
+	if track.Event == "" {
+		return errors.New("track has no event")
+	}
+	if track.UserId == "" && track.AnonymousId == "" {
+		return errors.New("track has neither userId nor anonymousId")
+	}
+
+	batch := analytics.Batch{
+		Context:  analytics.DefaultContext,
+		Messages: []interface{}{track},
+		Message: analytics.Message{
+			MessageId: uuid.New().String(),
+			SentAt:    time.Now().Format(segmentTimeFormat),
+		},
+	}
+	b, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", s.endpoint+"/v1/batch", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("content-type", "application/json")
+	req.SetBasicAuth(s.key, "")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	log.Debugf("segment: %s track %s: %s", reporter, track.MessageId, resp.Status)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("segment: %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *segmentSink) Close() error {
+	return nil
+}
+
+// stdoutSink collects the tracks of a run and prints them as a single JSON
+// object keyed by reporter name on Close.
+type stdoutSink struct {
+	out    io.Writer
+	tracks map[string]*analytics.Track
+}
+
+func newStdoutSink(out io.Writer) *stdoutSink {
+	return &stdoutSink{
+		out:    out,
+		tracks: make(map[string]*analytics.Track),
+	}
+}
+
+func (s *stdoutSink) Name() string {
+	return "stdout"
+}
+
+func (s *stdoutSink) Send(reporter string, track *analytics.Track) error {
+	s.tracks[reporter] = track
+	return nil
+}
+
+func (s *stdoutSink) Close() error {
+	jsonStr, err := json.MarshalIndent(s.tracks, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(s.out, string(jsonStr))
+	return err
+}
+
+// fileSink appends every track to a file as a line of JSON.
+type fileSink struct {
+	path string
+	file *os.File
+}
+
+func (s *fileSink) Name() string {
+	return "file:" + s.path
+}
+
+func (s *fileSink) Send(reporter string, track *analytics.Track) error {
+	if s.file == nil {
+		f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		s.file = f
+	}
+	return json.NewEncoder(s.file).Encode(track)
+}
+
+func (s *fileSink) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
+
+// webhookSink POSTs every track as JSON to a URL.
+type webhookSink struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func (s *webhookSink) Name() string {
+	return "webhook:" + s.url
+}
+
+func (s *webhookSink) Send(reporter string, track *analytics.Track) error {
+	b, err := json.Marshal(track)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", s.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("content-type", "application/json")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s: %s", s.url, resp.Status)
+	}
+	return nil
+}
+
+func (s *webhookSink) Close() error {
+	return nil
+}
@@ -0,0 +1,190 @@
+// +build unit
+
+package signal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/dcos/dcos-signal/config"
+	"gopkg.in/segmentio/analytics-go.v2"
+)
+
+func TestMakeSinks(t *testing.T) {
+	c := config.DefaultConfig()
+
+	sinks, err := makeSinks(c)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if s, ok := sinks[0].(*segmentSink); len(sinks) != 1 || !ok {
+		t.Error("Expected only the segment sink by default, got", sinks)
+	} else if s.endpoint != analytics.Endpoint || s.key != c.SegmentKey || s.client == nil {
+		t.Errorf("Expected segment sink posting to %s, got %+v", analytics.Endpoint, s)
+	}
+	closeSinks(sinks)
+
+	c.Sinks = []config.SinkConfig{
+		{Type: "segment"},
+		{Type: "file", Path: "/tmp/tracks"},
+		{Type: "webhook", URL: "http://localhost/tracks"},
+	}
+	sinks, err = makeSinks(c)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if len(sinks) != 3 {
+		t.Error("Expected 3 sinks, got", len(sinks))
+	}
+	closeSinks(sinks)
+
+	c.FlagTest = true
+	sinks, _ = makeSinks(c)
+	if _, ok := sinks[0].(*stdoutSink); len(sinks) != 1 || !ok {
+		t.Error("Expected only the stdout sink in test mode, got", sinks)
+	}
+
+	c.FlagTest = false
+	for _, bad := range []config.SinkConfig{
+		{Type: "carrier-pigeon"},
+		{Type: "file"},
+		{Type: "webhook", URL: "localhost/tracks"},
+	} {
+		c.Sinks = []config.SinkConfig{bad}
+		if _, err := makeSinks(c); err == nil {
+			t.Errorf("Expected error for sink %+v, got nil", bad)
+		}
+	}
+}
+
+func TestStdoutSink(t *testing.T) {
+	var out bytes.Buffer
+	s := newStdoutSink(&out)
+	s.Send("foo", &analytics.Track{Event: "foo_event"})
+
+	if err := s.Close(); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	data := map[string]*analytics.Track{}
+	if err := json.Unmarshal(out.Bytes(), &data); err != nil {
+		t.Fatal("Expected JSON output, got", out.String())
+	}
+	if data["foo"].Event != "foo_event" {
+		t.Error("Expected foo_event keyed by reporter, got", data)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	f, _ := ioutil.TempFile(os.TempDir(), "")
+	f.Close()
+	defer os.Remove(f.Name())
+
+	s := &fileSink{path: f.Name()}
+	s.Send("foo", &analytics.Track{Event: "foo_event"})
+	s.Send("bar", &analytics.Track{Event: "bar_event"})
+	if err := s.Close(); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	f, _ = os.Open(f.Name())
+	defer f.Close()
+	var events []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var track analytics.Track
+		if err := json.Unmarshal(scanner.Bytes(), &track); err != nil {
+			t.Fatal("Expected a JSON track per line, got", scanner.Text())
+		}
+		events = append(events, track.Event)
+	}
+
+	if len(events) != 2 || events[0] != "foo_event" || events[1] != "bar_event" {
+		t.Error("Expected foo_event and bar_event, got", events)
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	var (
+		received analytics.Track
+		header   string
+	)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("x-collector-key")
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer hook.Close()
+
+	c := config.DefaultConfig()
+	s, err := makeSink(config.SinkConfig{
+		Type:    "webhook",
+		URL:     hook.URL,
+		Headers: map[string]string{"x-collector-key": "secret"},
+	}, c)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	if err := s.Send("foo", &analytics.Track{Event: "foo_event"}); err != nil {
+		t.Error("Expected nil error, got", err)
+	}
+	if received.Event != "foo_event" {
+		t.Error("Expected foo_event, got", received.Event)
+	}
+	if header != "secret" {
+		t.Error("Expected configured header, got", header)
+	}
+}
+
+func TestSegmentSink(t *testing.T) {
+	var (
+		batch analytics.Batch
+		key   string
+	)
+	segment := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/batch" {
+			http.NotFound(w, r)
+			return
+		}
+		key, _, _ = r.BasicAuth()
+		json.NewDecoder(r.Body).Decode(&batch)
+	}))
+	defer segment.Close()
+
+	s := &segmentSink{endpoint: segment.URL, key: "12345", client: http.DefaultClient}
+	track := &analytics.Track{Event: "foo_event", AnonymousId: "anon"}
+	stampTrack(track)
+	messageID, timestamp := track.MessageId, track.Timestamp
+	if err := s.Send("foo", track); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	if key != "12345" {
+		t.Error("Expected segment key as basic auth user, got", key)
+	}
+	if len(batch.Messages) != 1 {
+		t.Fatal("Expected 1 message in batch, got", len(batch.Messages))
+	}
+	msg := batch.Messages[0].(map[string]interface{})
+	if msg["messageId"] != messageID || msg["timestamp"] != timestamp || msg["type"] != "track" {
+		t.Error("Expected track with its message ID and timestamp, got", msg)
+	}
+	if track.MessageId != messageID || track.Timestamp != timestamp {
+		t.Error("Expected Send not to modify the track, got", track.MessageId, track.Timestamp)
+	}
+
+	if err := s.Send("foo", &analytics.Track{Event: "foo_event"}); err == nil {
+		t.Error("Expected error for track without user, got nil")
+	}
+
+	s.endpoint = segment.URL + "/unavailable"
+	if err := s.Send("foo", track); err == nil {
+		t.Error("Expected error for failed delivery, got nil")
+	}
+}
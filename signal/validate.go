@@ -0,0 +1,87 @@
+package signal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/dcos/dcos-signal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// executeValidate loads the config from all sources, writes every problem it
+// finds to out and returns the exit code for the validate command.
+func executeValidate(args []string, out io.Writer) int {
+	// Every problem is reported below, most of them would be logged twice.
+	log.SetLevel(log.FatalLevel)
+
+	c, errs := config.ValidateArgs(args)
+	errs = append(errs, validateReporters(c)...)
+	if len(errs) == 0 {
+		fmt.Fprintln(out, "Config is valid")
+		return 0
+	}
+	for _, err := range errs {
+		fmt.Fprintln(out, "ERROR:", err)
+	}
+	return 1
+}
+
+// executePrintConfig writes the effective config from all sources to out as
+// JSON, with secrets masked, and returns the exit code for the print-config
+// command. Problems loading the config are logged, after which the config is
+// printed as far as it could be loaded.
+func executePrintConfig(args []string, out io.Writer) int {
+	c, errs := config.ParseArgsReturnConfig(args)
+	for _, err := range errs {
+		log.Error(err)
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(c.Masked()); err != nil {
+		log.Error(err)
+		return 1
+	}
+	if len(errs) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// validateReporters returns an error for every reporter and sink c sets up
+// that could not run: reporters without endpoints or with a different number
+// than they need, health detail with an invalid redact pattern, and sinks that
+// are not configured correctly.
+func validateReporters(c config.Config) []error {
+	var errs []error
+	reporters, err := makeReporters(c)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, r := range reporters {
+		endpoints := len(r.GetEndpoints())
+		if ec, ok := r.(EndpointCounter); ok && endpoints != ec.EndpointCount() {
+			errs = append(errs, fmt.Errorf("reporter %s has %d endpoints, needs %d", r.GetName(), endpoints, ec.EndpointCount()))
+		} else if endpoints == 0 {
+			errs = append(errs, fmt.Errorf("reporter %s has no endpoints", r.GetName()))
+		}
+	}
+
+	// Diagnostics sends no health detail, rather than failing, if a redact
+	// pattern does not compile.
+	if c.HealthDetail.Enabled {
+		if _, err := newRedactor(c.HealthDetail.Redact, nil); err != nil {
+			errs = append(errs, fmt.Errorf("health_detail: %s", err))
+		}
+	}
+
+	for i, sc := range c.Sinks {
+		// Sinks only open files and connections once they send, so there is
+		// nothing to close.
+		if _, err := makeSink(sc, c); err != nil {
+			errs = append(errs, fmt.Errorf("sinks[%d]: %s", i, err))
+		}
+	}
+	return errs
+}
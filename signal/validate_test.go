@@ -0,0 +1,96 @@
+// +build unit
+
+package signal
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dcos/dcos-signal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestValidateReporters(t *testing.T) {
+	c := config.DefaultConfig()
+	c.DiagnosticsURLs = []string{"http://localhost/system/health/v1/report"}
+	c.CosmosURLs = []string{"http://localhost/package/list"}
+	c.MesosURLs = []string{"http://localhost/frameworks"}
+	c.Sinks = []config.SinkConfig{{Type: "file"}, {Type: "stdout"}}
+
+	errs := validateReporters(c)
+	if len(errs) != 2 {
+		t.Fatal("Expected 2 errors, got", errs)
+	}
+	if errs[0].Error() != "reporter mesos has 1 endpoints, needs 2" {
+		t.Error("Expected error for mesos endpoints, got", errs[0])
+	}
+	if errs[1].Error() != "sinks[0]: file sink needs a path" {
+		t.Error("Expected error for file sink, got", errs[1])
+	}
+
+	c.MesosURLs = append(c.MesosURLs, "http://localhost/metrics/snapshot")
+	c.Sinks = nil
+	if errs := validateReporters(c); len(errs) != 0 {
+		t.Error("Expected no errors, got", errs)
+	}
+
+	c.HealthDetail.Enabled = true
+	c.HealthDetail.Redact.Patterns = []string{"("}
+	if errs := validateReporters(c); len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), "health_detail: invalid redact pattern") {
+		t.Error("Expected error for the redact pattern, got", errs)
+	}
+}
+
+func TestExecuteValidateAndPrintConfig(t *testing.T) {
+	defer log.SetLevel(log.GetLevel())
+
+	dir, err := ioutil.TempDir("", "signal-validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	clusterID := filepath.Join(dir, "cluster-id")
+	ioutil.WriteFile(clusterID, []byte("12345"), 0600)
+	valid := filepath.Join(dir, "valid.json")
+	ioutil.WriteFile(valid, []byte(`{
+  "diagnostics_urls": ["http://localhost/system/health/v1/report"],
+  "cosmos_urls": ["http://localhost/package/list"],
+  "mesos_urls": ["http://localhost/frameworks", "http://localhost/metrics/snapshot"],
+  "customer_key": "secret"
+}`), 0600)
+	invalid := filepath.Join(dir, "invalid.json")
+	ioutil.WriteFile(invalid, []byte(`{"cosmos_urls": ["localhost"], "bogus": true}`), 0600)
+
+	var out bytes.Buffer
+	if code := executeValidate([]string{"-c", valid, "-cluster-id-path", clusterID}, &out); code != 0 {
+		t.Error("Expected exit code 0 for valid config, got", code, out.String())
+	}
+
+	out.Reset()
+	if code := executeValidate([]string{"-c", invalid, "-cluster-id-path", clusterID}, &out); code != 1 {
+		t.Error("Expected exit code 1 for invalid config, got", code)
+	}
+	for _, expected := range []string{"unknown key bogus", "cosmos_urls", "reporter diagnostics has 0 endpoints"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected output to mention %q, got %s", expected, out.String())
+		}
+	}
+
+	out.Reset()
+	if code := executePrintConfig([]string{"-c", valid, "-cluster-id-path", clusterID}, &out); code != 0 {
+		t.Error("Expected exit code 0, got", code)
+	}
+	var printed map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &printed); err != nil {
+		t.Fatal("Expected JSON output, got", err, out.String())
+	}
+	if printed["customer_key"] != "********" || printed["cluster_id"] != "12345" {
+		t.Error("Expected masked customer key and cluster ID, got", printed["customer_key"], printed["cluster_id"])
+	}
+}